@@ -0,0 +1,88 @@
+package rescript
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// NewAsciiDocComposer creates a new composer which generates output in
+// AsciiDoc format.
+//
+// The notebook title is the document title and each page is a section.
+// Line breaks from the handwriting are preserved.
+func NewAsciiDocComposer(opts ...ComposeOption) ComposeFunc {
+	o := newComposeOptions(opts)
+	return func(w io.Writer, m Metadata, r map[string]*Node) error {
+		return composeAsciiDoc(w, m, r, o)
+	}
+}
+
+func composeAsciiDoc(w io.Writer, m Metadata, r map[string]*Node, o composeOptions) error {
+	var err error
+	sw := stringWriter{w}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("= %v\n", m.Title))
+	b.WriteString(":hardbreaks-option:\n")
+	if m.ID != "" {
+		b.WriteString(fmt.Sprintf(":notebook-id: %v\n", m.ID))
+	}
+
+	_, err = sw.WriteString(b.String())
+	if err != nil {
+		return err
+	}
+
+	for i, pageID := range m.PageIDs {
+		tail, ok := r[pageID]
+		if ok {
+			err = asciiDocPage(sw, m.PageNumber(i), o.sections(m, pageID, tail), o.structure)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func asciiDocPage(sw io.StringWriter, number int, sections []section, structure bool) error {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("\n[[%v]]\n== Page %d\n", pageAnchor(number), number))
+
+	for _, s := range sections {
+		depth := 2
+		if s.name != "" {
+			depth = 3
+			b.WriteString(fmt.Sprintf("\n=== %v\n", s.name))
+		}
+
+		text := asciiDocStyle(depth).text(s.tokens, structure)
+		if text != "" {
+			b.WriteString("\n" + text + "\n")
+		}
+	}
+
+	_, err := sw.WriteString(b.String())
+	return err
+}
+
+// asciiDocStyle formats blocks of text for AsciiDoc.
+// Headings are placed below the given depth.
+func asciiDocStyle(depth int) blockStyle {
+	return blockStyle{
+		heading: func(level int) string {
+			return strings.Repeat("=", depth+level) + " "
+		},
+		bullet: "* ",
+		number: numbered,
+		task: func(checked bool) string {
+			if checked {
+				return "* [x] "
+			}
+			return "* [ ] "
+		},
+	}
+}
@@ -0,0 +1,63 @@
+package rescript
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComposeAsciiDoc(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+
+	node := buildSampleList("foo", " ", "bar", "\n", "1.", " ", "one", "\n", "2.", " ", "two", "\n", "note")
+	for n := node.Ahead(12); n != nil; n = n.Next() {
+		n.Token().layer = 1
+	}
+
+	m := Metadata{
+		ID:      "abc",
+		Title:   "My Title",
+		PageIDs: []string{"page0"},
+		Layers: map[string][]string{
+			"page0": []string{"Text", "Notes"},
+		},
+	}
+	nodes := map[string]*Node{"page0": node}
+
+	c := NewAsciiDocComposer(SplitLayers(), InferStructure())
+	err := c(&buf, m, nodes)
+	assert.Nil(err)
+
+	expected := `= My Title
+:hardbreaks-option:
+:notebook-id: abc
+
+[[page-1]]
+== Page 1
+
+=== Text
+
+foo bar
+
+1. one
+2. two
+
+=== Notes
+
+note
+`
+	assert.Equal(expected, buf.String())
+}
+
+func TestAsciiDocError(t *testing.T) {
+	assert := assert.New(t)
+
+	node := NewNode(NewToken("foo"))
+	w := failWriter{}
+
+	err := asciiDocPage(w, 2, composeOptions{}.sections(Metadata{}, "page", node), false)
+	assert.Error(err)
+}
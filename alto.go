@@ -0,0 +1,180 @@
+package rescript
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/akeil/rmtool/pkg/lines"
+)
+
+const altoNamespace = "http://www.loc.gov/standards/alto/ns-v4#"
+
+// NewALTOComposer creates a new composer which generates ALTO XML,
+// with the recognized words, their positions and alternative candidates.
+//
+// Positions are given in device pixels.
+// See: https://www.loc.gov/standards/alto/
+func NewALTOComposer(opts ...ComposeOption) ComposeFunc {
+	o := newComposeOptions(opts)
+	return func(w io.Writer, m Metadata, r map[string]*Node) error {
+		return composeALTO(w, m, r, o)
+	}
+}
+
+type altoDocument struct {
+	XMLName     xml.Name        `xml:"alto"`
+	Namespace   string          `xml:"xmlns,attr"`
+	Description altoDescription `xml:"Description"`
+	Pages       []altoPage      `xml:"Layout>Page"`
+}
+
+type altoDescription struct {
+	MeasurementUnit string `xml:"MeasurementUnit"`
+	FileName        string `xml:"sourceImageInformation>fileName"`
+	Software        string `xml:"OCRProcessing>ocrProcessingStep>processingSoftware>softwareName"`
+	Version         string `xml:"OCRProcessing>ocrProcessingStep>processingSoftware>softwareVersion"`
+}
+
+type altoPage struct {
+	ID         string     `xml:"ID,attr"`
+	Number     int        `xml:"PHYSICAL_IMG_NR,attr"`
+	Width      int        `xml:"WIDTH,attr"`
+	Height     int        `xml:"HEIGHT,attr"`
+	PrintSpace altoBlocks `xml:"PrintSpace"`
+}
+
+type altoBlocks struct {
+	Blocks []altoBlock `xml:"TextBlock"`
+}
+
+type altoBlock struct {
+	ID string `xml:"ID,attr"`
+	altoPosition
+	Lines []altoLine `xml:"TextLine"`
+}
+
+type altoLine struct {
+	ID string `xml:"ID,attr"`
+	altoPosition
+	Items []interface{}
+}
+
+type altoString struct {
+	XMLName xml.Name `xml:"String"`
+	ID      string   `xml:"ID,attr"`
+	Content string   `xml:"CONTENT,attr"`
+	altoPosition
+	Alternatives []string `xml:"ALTERNATIVE"`
+}
+
+type altoSpace struct {
+	XMLName xml.Name `xml:"SP"`
+}
+
+type altoPosition struct {
+	HPos   *int `xml:"HPOS,attr"`
+	VPos   *int `xml:"VPOS,attr"`
+	Width  *int `xml:"WIDTH,attr"`
+	Height *int `xml:"HEIGHT,attr"`
+}
+
+func composeALTO(w io.Writer, m Metadata, r map[string]*Node, o composeOptions) error {
+	doc := altoDocument{
+		Namespace: altoNamespace,
+		Description: altoDescription{
+			MeasurementUnit: "pixel",
+			FileName:        m.Title,
+			Software:        "rescript",
+			Version:         Version,
+		},
+		Pages: make([]altoPage, 0),
+	}
+
+	for i, pageID := range m.PageIDs {
+		tail, ok := r[pageID]
+		if ok {
+			doc.Pages = append(doc.Pages, altoPageFor(m.PageNumber(i), o.sections(m, pageID, tail)))
+		}
+	}
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	err = enc.Encode(doc)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+func altoPageFor(page int, sections []section) altoPage {
+	p := altoPage{
+		ID:     fmt.Sprintf("page_%d", page),
+		Number: page,
+		Width:  lines.MaxWidth,
+		Height: lines.MaxHeight,
+	}
+
+	lineNo := 0
+	wordNo := 0
+	for i, s := range sections {
+		lns := splitLines(s.tokens)
+		if len(lns) == 0 {
+			continue
+		}
+
+		block := altoBlock{
+			ID:           fmt.Sprintf("block_%d_%d", page, i+1),
+			altoPosition: altoPositionFor(linesBounds(lns)),
+		}
+		for _, line := range lns {
+			lineNo++
+			l := altoLine{
+				ID:           fmt.Sprintf("line_%d_%d", page, lineNo),
+				altoPosition: altoPositionFor(wordsBounds(line)),
+			}
+			for j, t := range line {
+				wordNo++
+				if j != 0 {
+					l.Items = append(l.Items, altoSpace{})
+				}
+				l.Items = append(l.Items, altoString{
+					ID:           fmt.Sprintf("word_%d_%d", page, wordNo),
+					Content:      t.String(),
+					altoPosition: altoPositionFor(t.Bounds()),
+					Alternatives: alternatives(t),
+				})
+			}
+			block.Lines = append(block.Lines, l)
+		}
+		p.PrintSpace.Blocks = append(p.PrintSpace.Blocks, block)
+	}
+
+	return p
+}
+
+// altoPositionFor converts a bounding box to ALTO attributes.
+// A zero bounding box results in no attributes.
+func altoPositionFor(b BoundingBox) altoPosition {
+	if b.IsZero() {
+		return altoPosition{}
+	}
+	round := func(f float64) *int {
+		i := int(math.Round(f))
+		return &i
+	}
+	return altoPosition{
+		HPos:   round(b.X),
+		VPos:   round(b.Y),
+		Width:  round(b.Width),
+		Height: round(b.Height),
+	}
+}
@@ -0,0 +1,61 @@
+package rescript
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComposeALTO(t *testing.T) {
+	assert := assert.New(t)
+
+	m := Metadata{
+		Title:   "My Title",
+		PageIDs: []string{"page0", "page1"},
+	}
+	n := buildSampleList("foo", " ", "b&r")
+	n.Token().bounds = BoundingBox{X: 100, Y: 100, Width: 120, Height: 50}
+	n.Ahead(2).Token().bounds = BoundingBox{X: 250, Y: 110, Width: 100, Height: 50}
+	n.Ahead(2).Token().candidates = []string{"b&r", "bar"}
+
+	var buf bytes.Buffer
+	c := NewALTOComposer()
+	err := c(&buf, m, map[string]*Node{"page0": n})
+	assert.Nil(err)
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<alto xmlns="http://www.loc.gov/standards/alto/ns-v4#">
+  <Description>
+    <MeasurementUnit>pixel</MeasurementUnit>
+    <sourceImageInformation>
+      <fileName>My Title</fileName>
+    </sourceImageInformation>
+    <OCRProcessing>
+      <ocrProcessingStep>
+        <processingSoftware>
+          <softwareName>rescript</softwareName>
+          <softwareVersion>` + Version + `</softwareVersion>
+        </processingSoftware>
+      </ocrProcessingStep>
+    </OCRProcessing>
+  </Description>
+  <Layout>
+    <Page ID="page_1" PHYSICAL_IMG_NR="1" WIDTH="1404" HEIGHT="1872">
+      <PrintSpace>
+        <TextBlock ID="block_1_1" HPOS="100" VPOS="100" WIDTH="250" HEIGHT="60">
+          <TextLine ID="line_1_1" HPOS="100" VPOS="100" WIDTH="250" HEIGHT="60">
+            <String ID="word_1_1" CONTENT="foo" HPOS="100" VPOS="100" WIDTH="120" HEIGHT="50"></String>
+            <SP></SP>
+            <String ID="word_1_2" CONTENT="b&amp;r" HPOS="250" VPOS="110" WIDTH="100" HEIGHT="50">
+              <ALTERNATIVE>bar</ALTERNATIVE>
+            </String>
+          </TextLine>
+        </TextBlock>
+      </PrintSpace>
+    </Page>
+  </Layout>
+</alto>
+`
+	assert.Equal(expected, buf.String())
+}
@@ -0,0 +1,44 @@
+package rescript
+
+import (
+	"archive/zip"
+	"io"
+)
+
+// archiveFile is a file in a zip based document format like EPUB or DOCX.
+type archiveFile struct {
+	name    string
+	content string
+}
+
+// writeArchive writes the given files to a zip archive.
+//
+// If mimetype is non-empty, it is written as the first file, uncompressed,
+// as required by EPUB and OpenDocument.
+func writeArchive(w io.Writer, mimetype string, files []archiveFile) error {
+	z := zip.NewWriter(w)
+
+	if mimetype != "" {
+		mt, err := z.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(mt, mimetype)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, f := range files {
+		fw, err := z.Create(f.name)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(fw, f.content)
+		if err != nil {
+			return err
+		}
+	}
+
+	return z.Close()
+}
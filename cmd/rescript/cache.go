@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func doCache(s settings, clear bool) error {
+	caches := []struct {
+		name    string
+		pattern string
+	}{
+		{"Downloaded notebooks", filepath.Join(s.CacheDir, "*.zip")},
+		{"Recognition results", filepath.Join(s.hwrCache(), "*.cache.json")},
+	}
+
+	for _, c := range caches {
+		paths, err := filepath.Glob(c.pattern)
+		if err != nil {
+			return err
+		}
+
+		var size int64
+		for _, p := range paths {
+			info, err := os.Stat(p)
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+
+		fmt.Printf("%v: %d files, %v in %q\n", c.name, len(paths), formatSize(size), filepath.Dir(c.pattern))
+
+		if clear {
+			for _, p := range paths {
+				err = os.Remove(p)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if clear {
+		success("Cleared caches.")
+	}
+	return nil
+}
+
+// formatSize formats a number of bytes for humans, e.g. "1.2 MB".
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGT"[exp])
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"gopkg.in/yaml.v2"
+)
+
+const configTemplate = `# Configuration for rescript
+datadir: {{ .DataDir }}
+cachedir: {{ .CacheDir }}
+
+# Credentials for the MyScript API,
+# see https://developer.myscript.com/
+appkey: ""
+hmackey: ""
+
+# Maximum number of requests to the MyScript API per month,
+# see "rescript usage"
+# monthlylimit: 2000
+
+# Directory with the brush images for PDF output
+# renderdir:
+
+# Estimation of stroke timing if the drawing has no timestamps,
+# times are in milliseconds
+# timing:
+#   speedfactor: 20000
+#   minspeed: 0.01
+#   strokegap: 50000
+
+# Rules to select which strokes are recognized, e.g.
+# strokes:
+#   - brushes: [pencil]
+#     colors: [gray]
+#     exclude: true
+`
+
+// doConfigShow prints the configuration with the keys masked.
+func doConfigShow(s settings) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	info("Configuration from %q", path)
+
+	s.AppKey = mask(s.AppKey)
+	s.HmacKey = mask(s.HmacKey)
+
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	fmt.Print(string(data))
+	return nil
+}
+
+// doConfigInit creates a configuration file with default settings.
+// An existing file is not changed.
+func doConfigInit() error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stat(path)
+	if err == nil {
+		return fmt.Errorf("configuration file %q already exists", path)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	s, err := defaultSettings()
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	err = template.Must(template.New("config").Parse(configTemplate)).Execute(f, s)
+	if err != nil {
+		return err
+	}
+
+	success("Created %q, add your MyScript keys to complete the setup.", path)
+	return nil
+}
+
+// doConfigValidate checks the configuration file and reports all problems.
+func doConfigValidate() error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+
+	problems, err := validateConfig(path)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range problems {
+		problem("%v", p)
+	}
+	if len(problems) != 0 {
+		return fmt.Errorf("found %d problems in %q", len(problems), path)
+	}
+
+	success("Configuration %q is valid.", path)
+	return nil
+}
+
+// validateConfig reads the configuration file at the given path
+// and returns a list of problems.
+// Unknown keys and invalid YAML are returned as an error.
+func validateConfig(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no configuration file at %q, create one with \"rescript config init\"", path)
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var s settings
+	d := yaml.NewDecoder(f)
+	d.SetStrict(true)
+	err = d.Decode(&s)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := make([]string, 0)
+	if s.DataDir == "" {
+		problems = append(problems, "datadir is not set")
+	}
+	if s.CacheDir == "" {
+		problems = append(problems, "cachedir is not set")
+	}
+	if s.MonthlyLimit < 0 {
+		problems = append(problems, "monthlylimit must not be negative")
+	}
+	if s.Timing.SpeedFactor < 0 || s.Timing.MinSpeed < 0 || s.Timing.StrokeGap < 0 {
+		problems = append(problems, "timing values must not be negative")
+	}
+	if s.AppKey == "" {
+		problems = append(problems, "appkey is not set")
+	}
+	if s.HmacKey == "" {
+		problems = append(problems, "hmackey is not set")
+	}
+	if s.RenderDir != "" {
+		info, err := os.Stat(s.RenderDir)
+		if err != nil || !info.IsDir() {
+			problems = append(problems, fmt.Sprintf("renderdir %q is not a directory", s.RenderDir))
+		}
+	}
+	for i, rule := range s.Strokes {
+		_, err := rule.Filter()
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("stroke rule %d: %v", i+1, err))
+		}
+	}
+
+	return problems, nil
+}
+
+// defaultSettings returns settings with the default data and cache dirs.
+func defaultSettings() (settings, error) {
+	var s settings
+
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return s, err
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	s.DataDir = filepath.Join(dataHome, "hwr")
+
+	cacheHome, err := os.UserCacheDir()
+	if err != nil {
+		return s, err
+	}
+	s.CacheDir = filepath.Join(cacheHome, "hwr")
+
+	return s, nil
+}
+
+// mask hides all but the first characters of a secret.
+func mask(s string) string {
+	if s == "" {
+		return s
+	}
+	if len(s) <= 4 {
+		return "…"
+	}
+	return s[:4] + "…"
+}
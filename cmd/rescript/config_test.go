@@ -0,0 +1,102 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "rescript-test")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yaml")
+
+	const valid = "datadir: /data\ncachedir: /cache\nappkey: app\nhmackey: hmac\n"
+
+	cases := []struct {
+		name     string
+		config   string
+		expected []string
+	}{
+		{"valid", valid, []string{}},
+		{"valid with options", valid + "monthlylimit: 100\nrenderdir: " + dir + "\n" +
+			"timing:\n  speedfactor: 10000\n  strokegap: 1000\n" +
+			"strokes:\n  - brushes: [pencil]\n    colors: [gray]\n    sizes: [small]\n    exclude: true\n", []string{}},
+		{"missing keys", "datadir: /data\ncachedir: /cache\n", []string{"appkey is not set", "hmackey is not set"}},
+		{"empty", "{}", []string{"datadir is not set", "cachedir is not set", "appkey is not set", "hmackey is not set"}},
+		{"negative limit", valid + "monthlylimit: -1\n", []string{"monthlylimit must not be negative"}},
+		{"negative speed factor", valid + "timing:\n  speedfactor: -1\n", []string{"timing values must not be negative"}},
+		{"negative min speed", valid + "timing:\n  minspeed: -0.5\n", []string{"timing values must not be negative"}},
+		{"negative stroke gap", valid + "timing:\n  strokegap: -1\n", []string{"timing values must not be negative"}},
+		{"bad brush", valid + "strokes:\n  - brushes: [crayon]\n", []string{`stroke rule 1: unknown brush "crayon"`}},
+		{"bad size", valid + "strokes:\n  - brushes: [pencil]\n  - sizes: [huge]\n", []string{`stroke rule 2: unknown brush size "huge"`}},
+		{"renderdir is a file", valid + "renderdir: " + path + "\n", []string{`renderdir "` + path + `" is not a directory`}},
+		{"renderdir does not exist", valid + "renderdir: /no/such/dir\n", []string{`renderdir "/no/such/dir" is not a directory`}},
+	}
+	for _, c := range cases {
+		assert.Nil(ioutil.WriteFile(path, []byte(c.config), 0644))
+		problems, err := validateConfig(path)
+		assert.Nil(err, c.name)
+		assert.Equal(c.expected, problems, c.name)
+	}
+
+	// a bad color in a rule
+	assert.Nil(ioutil.WriteFile(path, []byte(valid+"strokes:\n  - colors: [purple]\n"), 0644))
+	problems, err := validateConfig(path)
+	assert.Nil(err)
+	assert.Equal(1, len(problems))
+	assert.True(strings.HasPrefix(problems[0], "stroke rule 1:"), problems[0])
+
+	// unknown keys and invalid YAML are errors
+	for _, config := range []string{valid + "appkeys: typo\n", valid + "timing:\n  gap: 1\n", "datadir: [\n"} {
+		assert.Nil(ioutil.WriteFile(path, []byte(config), 0644))
+		_, err = validateConfig(path)
+		assert.Error(err, config)
+	}
+
+	_, err = validateConfig(filepath.Join(dir, "missing.yaml"))
+	assert.Error(err)
+}
+
+func TestConfigTemplate(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "rescript-test")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yaml")
+
+	f, err := os.Create(path)
+	assert.Nil(err)
+	s := settings{DataDir: "/data", CacheDir: "/cache"}
+	assert.Nil(template.Must(template.New("config").Parse(configTemplate)).Execute(f, s))
+	assert.Nil(f.Close())
+
+	// only the keys are missing in a new configuration
+	problems, err := validateConfig(path)
+	assert.Nil(err)
+	assert.Equal([]string{"appkey is not set", "hmackey is not set"}, problems)
+}
+
+func TestMask(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := map[string]string{
+		"":                 "",
+		"a":                "…",
+		"abcd":             "…",
+		"abcde":            "abcd…",
+		"0123456789abcdef": "0123…",
+	}
+	for in, expected := range cases {
+		assert.Equal(expected, mask(in), in)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/akeil/rmtool"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/akeil/rescript"
+)
+
+// dryRun shows the requests that would be sent to the MyScript API for the
+// given notebooks, without sending them.
+//
+// Notebooks are downloaded to count the strokes, which is free.
+func dryRun(j *job, nodes []*rmtool.Node) error {
+	estimates := make([][]rescript.PageEstimate, len(nodes))
+	var group errgroup.Group
+	for i, n := range nodes {
+		i, n := i, n
+		group.Go(func() error {
+			doc, opts, _, err := j.download(n)
+			if err != nil {
+				return err
+			}
+			estimates[i], err = j.rec.Estimate(doc, j.lang, opts...)
+			return err
+		})
+	}
+	err := group.Wait()
+	if err != nil {
+		return err
+	}
+
+	var pages, requests, cached int
+	for i, n := range nodes {
+		fmt.Printf("%v (%v)\n", strings.Join(append(folderPath(n), n.Name()), "/"), n.ID())
+		for _, p := range estimates[i] {
+			fmt.Printf("  page %d: %v\n", p.Number, describePage(p))
+			pages++
+			for _, r := range p.Requests {
+				requests++
+				if r.Cached {
+					cached++
+				}
+			}
+		}
+	}
+
+	fmt.Printf("\n%d notebooks, %d pages, %d requests (%d cached): %d requests to the MyScript API\n",
+		len(nodes), pages, requests, cached, requests-cached)
+	return nil
+}
+
+func describePage(p rescript.PageEstimate) string {
+	if len(p.Requests) == 0 {
+		return "no strokes"
+	}
+
+	var strokes, points, cached int
+	for _, r := range p.Requests {
+		strokes += r.Strokes
+		points += r.Points
+		if r.Cached {
+			cached++
+		}
+	}
+
+	s := fmt.Sprintf("%d requests", len(p.Requests))
+	if len(p.Requests) == 1 {
+		s = "1 request"
+	}
+	switch cached {
+	case 0:
+	case len(p.Requests):
+		s += " (cached)"
+	default:
+		s += fmt.Sprintf(" (%d cached)", cached)
+	}
+	return fmt.Sprintf("%v, %d strokes, %d points", s, strokes, points)
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/akeil/rmtool"
+	"gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/akeil/rescript"
+)
+
+// convertFlags holds the command line options that control how notebooks
+// are recognized and which output is generated.
+type convertFlags struct {
+	format      string
+	lang        string
+	layers      bool
+	exclude     []string
+	blocks      bool
+	crop        string
+	css         string
+	template    string
+	frontMatter bool
+	structure   bool
+	pages       string
+	last        int
+}
+
+// addConvertFlags adds the flags for conversion to the given command.
+func addConvertFlags(cmd *kingpin.CmdClause, f *convertFlags) {
+	cmd.Flag("format", "Output format").Short('f').Default("txt").EnumVar(&f.format, "txt", "md", "org", "adoc", "html", "epub", "docx", "odt", "pdf", "hocr", "alto", "json")
+	cmd.Flag("lang", "Language of the notebook").Short('l').Default("en").StringVar(&f.lang)
+	cmd.Flag("layers", "Recognize each layer separately and output layers as sections").BoolVar(&f.layers)
+	cmd.Flag("exclude-layer", "Name of a layer to exclude from the output").StringsVar(&f.exclude)
+	cmd.Flag("blocks", "Split pages into blocks of text (columns, margin notes) before recognition").BoolVar(&f.blocks)
+	cmd.Flag("crop", "Only recognize the region \"X,Y,WIDTH,HEIGHT\" (in pixels)").StringVar(&f.crop)
+	cmd.Flag("css", "Stylesheet to embed in HTML output").ExistingFileVar(&f.css)
+	cmd.Flag("template", "Generate output from a text/template file or a built-in template (plaintext, markdown)").StringVar(&f.template)
+	cmd.Flag("front-matter", "Start markdown output with a YAML front matter").BoolVar(&f.frontMatter)
+	cmd.Flag("pages", "Only recognize these pages, e.g. \"3-5,9\"").StringVar(&f.pages)
+	cmd.Flag("last", "Only recognize the last N pages").IntVar(&f.last)
+	cmd.Flag("structure", "Detect headings and lists in markdown, org, adoc, docx and odt output").BoolVar(&f.structure)
+}
+
+// A job recognizes notebooks from a repository and composes the output
+// according to the conversion flags.
+type job struct {
+	repo     rmtool.Repository
+	rec      *rescript.Recognizer
+	settings settings
+	flags    convertFlags
+	lang     rescript.LanguageCode
+	opts     []rescript.Option
+	copts    []rescript.ComposeOption
+	compose  rescript.ComposeFunc
+	pipeline rescript.PipelineFunc
+	// pages and last select pages, all pages are used if both are unset
+	pages []pageRange
+	last  int
+	// requireTags is set if notebooks are selected by tag,
+	// otherwise notebooks whose tags cannot be read are converted without tags
+	requireTags bool
+}
+
+func newJob(s settings, f convertFlags, repo rmtool.Repository) (*job, error) {
+	lc, ok := langs[f.lang]
+	if !ok {
+		return nil, fmt.Errorf("invalid language %q", f.lang)
+	}
+
+	pages, err := parsePages(f.pages)
+	if err != nil {
+		return nil, err
+	}
+	if f.last < 0 {
+		return nil, fmt.Errorf("invalid number of pages %d", f.last)
+	}
+
+	// layers are skipped before recognition, so that this also works
+	// if the layers are not recognized separately
+	opts := []rescript.Option{rescript.SkipLayers(f.exclude...)}
+	var copts []rescript.ComposeOption
+	if f.layers {
+		opts = append(opts, rescript.SeparateLayers())
+		copts = append(copts, rescript.SplitLayers())
+	}
+	if f.frontMatter {
+		copts = append(copts, rescript.FrontMatter())
+	}
+	if f.structure {
+		copts = append(copts, rescript.InferStructure())
+	}
+	if f.blocks {
+		opts = append(opts, rescript.SegmentBlocks(rescript.DefaultBlockGap))
+	}
+
+	conv, err := newConverter(s, f.crop)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, rescript.WithConverter(conv))
+
+	var cmp rescript.ComposeFunc
+	if f.template != "" {
+		cmp, err = templateComposer(f.template, copts...)
+	} else {
+		cmp, err = selectComposer(s, f.format, f.css, copts...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	usage, err := rescript.LoadUsage(s.usagePath())
+	if err != nil {
+		return nil, err
+	}
+	usage.MonthlyLimit = s.MonthlyLimit
+	rec := rescript.NewRecognizer(s.AppKey, s.HmacKey, s.hwrCache())
+	rec.TrackUsage(usage)
+	if logFormat == logJSON {
+		rec.OnProgress(logEvent)
+	}
+
+	return &job{
+		repo:     repo,
+		rec:      rec,
+		settings: s,
+		flags:    f,
+		lang:     lc,
+		opts:     opts,
+		copts:    copts,
+		compose:  cmp,
+		pipeline: rescript.BuildPipeline(rescript.Dehyphenate),
+		pages:    pages,
+		last:     f.last,
+	}, nil
+}
+
+// download reads a notebook and returns the recognition options for it.
+//
+// If only some pages are selected, it also returns their numbers.
+func (j *job) download(n *rmtool.Node) (*rmtool.Document, []rescript.Option, []int, error) {
+	progress("download notebook %q", n.Name())
+	doc, err := rmtool.ReadDocument(j.repo, n)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// copy, the options are shared between goroutines
+	opts := append([]rescript.Option{}, j.opts...)
+	var numbers []int
+	if len(j.pages) != 0 || j.last != 0 {
+		numbers = pageNumbers(j.pages, j.last, len(doc.Pages()))
+		if len(numbers) == 0 {
+			return nil, nil, nil, fmt.Errorf("no pages selected in %q with %d pages", n.Name(), len(doc.Pages()))
+		}
+		opts = append(opts, rescript.SelectPages(numbers...))
+	}
+
+	return doc, opts, numbers, nil
+}
+
+// recognize downloads a notebook and recognizes the handwriting.
+func (j *job) recognize(n *rmtool.Node) (rescript.Metadata, map[string]*rescript.Node, error) {
+	var m rescript.Metadata
+
+	doc, opts, numbers, err := j.download(n)
+	if err != nil {
+		return m, nil, err
+	}
+
+	progress("recognize handwriting (%v) for %q", j.flags.lang, n.Name())
+	results, err := j.rec.Recognize(doc, j.lang, opts...)
+	if err != nil {
+		return m, nil, err
+	}
+
+	for k, node := range results {
+		results[k] = j.pipeline(node)
+	}
+
+	m = rescript.NewMetadata(doc)
+	if numbers != nil {
+		m = m.Subset(numbers)
+	}
+	m.Path = folderPath(n)
+	m.Language = j.lang
+	m.Tags, err = j.tags(n)
+	if err != nil {
+		return m, nil, err
+	}
+
+	return m, results, nil
+}
+
+// tags reads the tags of a notebook for the metadata.
+//
+// Missing tags are only an error if notebooks are selected by tag.
+func (j *job) tags(n *rmtool.Node) ([]string, error) {
+	tags, err := readTags(j.repo, n)
+	if err != nil {
+		if j.requireTags {
+			return nil, err
+		}
+		problem("%v, continue without tags", err)
+		return nil, nil
+	}
+	return tags, nil
+}
+
+// convert recognizes a notebook and writes the output to the given path.
+func (j *job) convert(n *rmtool.Node, path string) error {
+	m, results, err := j.recognize(n)
+	if err != nil {
+		return err
+	}
+
+	err = writeFile(path, func(w io.Writer) error {
+		return j.compose(w, m, results)
+	})
+	if err != nil {
+		return err
+	}
+
+	success("write result to %q", path)
+	return nil
+}
+
+// extension returns the file extension for output files.
+func (j *job) extension() string {
+	return fileExtension(j.flags.format)
+}
+
+// writeFile creates or replaces the file at the given path.
+//
+// The content is written to a temporary file first, so that readers never
+// see a partially written file. Parent directories are created as needed.
+func writeFile(path string, write func(w io.Writer) error) error {
+	dir := filepath.Dir(path)
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(dir, ".rescript-*")
+	if err != nil {
+		return err
+	}
+	// no-op if the file was renamed
+	defer os.Remove(f.Name())
+
+	err = write(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
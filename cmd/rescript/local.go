@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/akeil/rmtool"
+)
+
+// localRepo is a read-only repository for a copy of the xochitl directory
+// from the tablet, e.g. synced with rsync.
+//
+// This would wrap rmtool/pkg/fs, but that package does not compile
+// at the version of rmtool we depend on.
+type localRepo struct {
+	base  string
+	pages map[string][]string
+	mx    sync.Mutex
+}
+
+func newLocalRepo(path string) rmtool.Repository {
+	return &localRepo{
+		base:  path,
+		pages: make(map[string][]string),
+	}
+}
+
+func (r *localRepo) List() ([]rmtool.Meta, error) {
+	files, err := ioutil.ReadDir(r.base)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]rmtool.Meta, 0)
+	for _, f := range files {
+		if filepath.Ext(f.Name()) != ".metadata" {
+			continue
+		}
+		id := strings.TrimSuffix(f.Name(), ".metadata")
+		m, err := readLocalMeta(filepath.Join(r.base, f.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read metadata for %q: %v", id, err)
+		}
+		// deleted on the tablet, but not yet removed
+		if m.Deleted {
+			continue
+		}
+		m.id = id
+		items = append(items, m)
+	}
+
+	return items, nil
+}
+
+// Reader opens a file below the base directory.
+// If the file does not exist, the error satisfies os.IsNotExist.
+func (r *localRepo) Reader(id string, version uint, path ...string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(append([]string{r.base}, path...)...))
+}
+
+// PagePrefix returns the ID of the page with the given index,
+// which is the name of the page files in the notebook directory.
+func (r *localRepo) PagePrefix(id string, index int) string {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	pages, ok := r.pages[id]
+	if !ok {
+		pages = r.readPages(id)
+		r.pages[id] = pages
+	}
+	if index < len(pages) {
+		return pages[index]
+	}
+	return id
+}
+
+// readPages reads the page IDs of a notebook from its .content file.
+func (r *localRepo) readPages(id string) []string {
+	var content struct {
+		Pages []string `json:"pages"`
+	}
+	f, err := os.Open(filepath.Join(r.base, id+".content"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	json.NewDecoder(f).Decode(&content)
+	return content.Pages
+}
+
+func (r *localRepo) Update(m rmtool.Meta) error {
+	return fmt.Errorf("local repository is read-only")
+}
+
+func (r *localRepo) Upload(d *rmtool.Document) error {
+	return fmt.Errorf("local repository is read-only")
+}
+
+// localMeta maps to the .metadata file of a notebook or folder.
+type localMeta struct {
+	id           string
+	Modified     string              `json:"lastModified"`
+	Ver          uint                `json:"version"`
+	ParentID     string              `json:"parent"`
+	Bookmarked   bool                `json:"pinned"`
+	Kind         rmtool.NotebookType `json:"type"`
+	VisibleName  string              `json:"visibleName"`
+	Deleted      bool                `json:"deleted"`
+	lastModified time.Time
+}
+
+func readLocalMeta(path string) (*localMeta, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m := &localMeta{}
+	err = json.NewDecoder(f).Decode(m)
+	if err != nil {
+		return nil, err
+	}
+
+	// the timestamp is a string with milliseconds since the epoch
+	if m.Modified != "" {
+		ms, err := strconv.ParseInt(m.Modified, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp %q", m.Modified)
+		}
+		m.lastModified = time.Unix(0, ms*int64(time.Millisecond)).UTC()
+	}
+
+	return m, nil
+}
+
+func (m *localMeta) ID() string                { return m.id }
+func (m *localMeta) Version() uint             { return m.Ver }
+func (m *localMeta) Name() string              { return m.VisibleName }
+func (m *localMeta) SetName(n string)          { m.VisibleName = n }
+func (m *localMeta) Type() rmtool.NotebookType { return m.Kind }
+func (m *localMeta) Pinned() bool              { return m.Bookmarked }
+func (m *localMeta) SetPinned(p bool)          { m.Bookmarked = p }
+func (m *localMeta) LastModified() time.Time   { return m.lastModified }
+func (m *localMeta) Parent() string            { return m.ParentID }
+
+func (m *localMeta) Validate() error {
+	if m.VisibleName == "" {
+		return fmt.Errorf("name must not be empty")
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalRepo(t *testing.T) {
+	assert := assert.New(t)
+
+	base, err := ioutil.TempDir("", "rescript-test")
+	assert.Nil(err)
+	defer os.RemoveAll(base)
+
+	write := func(name, content string) {
+		assert.Nil(ioutil.WriteFile(filepath.Join(base, name), []byte(content), 0644))
+	}
+	write("nb.metadata", `{"visibleName": "Notes", "type": "DocumentType", "version": 3, "lastModified": "1612085400000"}`)
+	write("nb.content", `{"pages": ["p1", "p2"]}`)
+	write("gone.metadata", `{"visibleName": "Old", "type": "DocumentType", "deleted": true}`)
+
+	r := newLocalRepo(base)
+	items, err := r.List()
+	assert.Nil(err)
+	assert.Equal(1, len(items))
+	assert.Equal("nb", items[0].ID())
+	assert.Equal("Notes", items[0].Name())
+	assert.Equal(uint(3), items[0].Version())
+	assert.Equal(int64(1612085400), items[0].LastModified().Unix())
+
+	// page files are named after the page ID
+	assert.Equal("p1", r.PagePrefix("nb", 0))
+	assert.Equal("p2", r.PagePrefix("nb", 1))
+	assert.Equal("nb", r.PagePrefix("nb", 2))
+
+	_, err = r.Reader("nb", 3, "nb", "p1.rm")
+	assert.True(os.IsNotExist(err))
+}
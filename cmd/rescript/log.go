@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/akeil/rescript"
+)
+
+// Marks are printed in front of messages in text mode to show their status.
+const (
+	checkmark = "\u2713"
+	crossmark = "\u2717"
+	ellipsis  = "\u2026"
+)
+
+// Log levels for --log-format json.
+const (
+	levelInfo  = "info"
+	levelError = "error"
+)
+
+const (
+	logText = "text"
+	logJSON = "json"
+)
+
+// logFormat is set with the --log-format flag.
+var logFormat = logText
+
+var logMx sync.Mutex
+
+// A logEntry is one line of output with --log-format json.
+type logEntry struct {
+	Time     time.Time `json:"time"`
+	Level    string    `json:"level"`
+	Message  string    `json:"message,omitempty"`
+	Event    string    `json:"event,omitempty"`
+	Document string    `json:"document,omitempty"`
+	Title    string    `json:"title,omitempty"`
+	PageID   string    `json:"pageId,omitempty"`
+	Page     int       `json:"page,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// info prints a message without a mark.
+func info(s string, params ...interface{}) {
+	logMessage(levelInfo, "", s, params...)
+}
+
+// progress prints a message about something that starts.
+func progress(s string, params ...interface{}) {
+	logMessage(levelInfo, ellipsis, s, params...)
+}
+
+// success prints a message about something that was done.
+func success(s string, params ...interface{}) {
+	logMessage(levelInfo, checkmark, s, params...)
+}
+
+// problem prints a message about something that went wrong.
+func problem(s string, params ...interface{}) {
+	logMessage(levelError, crossmark, s, params...)
+}
+
+// logMessage prints a message to STDERR.
+//
+// In text mode, the message starts with the given mark;
+// with --log-format json, it is written as a JSON object with the level.
+func logMessage(level, mark, s string, params ...interface{}) {
+	msg := fmt.Sprintf(s, params...)
+	if logFormat != logJSON {
+		if mark != "" {
+			msg = mark + " " + msg
+		}
+		writeLog(msg + "\n")
+		return
+	}
+
+	logEntryJSON(logEntry{
+		Time:    time.Now(),
+		Level:   level,
+		Message: msg,
+	})
+}
+
+// failure prints an error.
+//
+// With --log-format json, the error is set in its own field.
+func failure(err error) {
+	if logFormat != logJSON {
+		problem("Error: %v", err)
+		return
+	}
+	logEntryJSON(logEntry{
+		Time:    time.Now(),
+		Level:   levelError,
+		Message: "Error",
+		Error:   err.Error(),
+	})
+}
+
+// logEvent prints a progress event from the recognizer as JSON.
+func logEvent(e rescript.Event) {
+	entry := logEntry{
+		Time:     e.Time,
+		Level:    levelInfo,
+		Event:    e.Type.String(),
+		Document: e.Document,
+		Title:    e.Title,
+		PageID:   e.PageID,
+		Page:     e.Page,
+	}
+	if e.Err != nil {
+		entry.Level = levelError
+		entry.Error = e.Err.Error()
+	}
+	logEntryJSON(entry)
+}
+
+func logEntryJSON(e logEntry) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		// should not happen, all fields can be marshalled
+		writeLog(fmt.Sprintf("%v\n", e))
+		return
+	}
+	writeLog(string(data) + "\n")
+}
+
+// writeLog writes to STDERR, one message at a time.
+func writeLog(s string) {
+	logMx.Lock()
+	defer logMx.Unlock()
+	os.Stderr.WriteString(s)
+}
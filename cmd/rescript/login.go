@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/akeil/rmtool"
+	"github.com/akeil/rmtool/pkg/api"
+)
+
+func setupRepo(s settings) (rmtool.Repository, error) {
+	c, err := initClient(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return api.NewRepository(c, s.CacheDir), nil
+}
+
+func initClient(s settings) (*api.Client, error) {
+	// without a token, the client will be registered
+	token, err := loadToken(s.tokenPath())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	client := api.DefaultClient(token)
+
+	err = register(s, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+func register(s settings, c *api.Client) error {
+	if c.IsRegistered() {
+		return nil
+	}
+	return login(s, c)
+}
+
+// doLogin registers with the reMarkable cloud and replaces the device token.
+func doLogin(s settings) error {
+	err := login(s, api.DefaultClient(""))
+	if err != nil {
+		return err
+	}
+	success("Saved device token to %q", s.tokenPath())
+	return nil
+}
+
+func login(s settings, c *api.Client) error {
+	code, err := readInput("Enter one time code from https://my.remarkable.com/")
+	if err != nil {
+		return err
+	}
+
+	token, err := c.Register(code)
+	if err != nil {
+		return err
+	}
+
+	err = saveToken(s.tokenPath(), token)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func readInput(msg string) (string, error) {
+	var reply string
+
+	// a prompt, not a log message
+	fmt.Fprintf(os.Stderr, "%v: \n", msg)
+	_, err := fmt.Scanf("%s", &reply)
+
+	return reply, err
+}
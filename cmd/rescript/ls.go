@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/akeil/rmtool"
+	"golang.org/x/sync/errgroup"
+)
+
+func doLs(s settings, match string) error {
+	repo, err := setupRepo(s)
+	if err != nil {
+		return err
+	}
+
+	items, err := repo.List()
+	if err != nil {
+		return err
+	}
+
+	root := rmtool.BuildTree(items)
+	if match != "" {
+		root = root.Filtered(rmtool.IsDocument, rmtool.MatchName(match))
+	}
+
+	if len(root.Children) == 0 {
+		info("Found no matching notebooks.")
+		return nil
+	}
+
+	root.Sort(rmtool.DefaultSort)
+
+	progress("count pages")
+	pages, err := countPages(repo, root)
+	if err != nil {
+		return err
+	}
+
+	showTree(root, 0, pages)
+	return nil
+}
+
+// countPages reads the documents below the given node and returns the
+// number of pages, keyed by document ID.
+//
+// This downloads the notebooks, but they are cached for recognition.
+func countPages(repo rmtool.Repository, root *rmtool.Node) (map[string]int, error) {
+	pages := make(map[string]int)
+	var mx sync.Mutex
+
+	var group errgroup.Group
+	root.Walk(func(n *rmtool.Node) error {
+		if !n.IsLeaf() {
+			return nil
+		}
+		group.Go(func() error {
+			doc, err := rmtool.ReadDocument(repo, n)
+			if err != nil {
+				return err
+			}
+			mx.Lock()
+			defer mx.Unlock()
+			pages[n.ID()] = doc.PageCount()
+			return nil
+		})
+		return nil
+	})
+
+	return pages, group.Wait()
+}
+
+func showTree(n *rmtool.Node, level int, pages map[string]int) {
+	if level > 0 {
+		indent := strings.Repeat("  ", level-1)
+		if n.IsLeaf() {
+			fmt.Printf("%v- %v (%v) %v\n", indent, n.Name(), pageCount(pages[n.ID()]), n.ID())
+		} else {
+			fmt.Printf("%v+ %v\n", indent, n.Name())
+		}
+	}
+
+	if !n.IsLeaf() {
+		for _, c := range n.Children {
+			showTree(c, level+1, pages)
+		}
+	}
+}
+
+func pageCount(n int) string {
+	if n == 1 {
+		return "1 page"
+	}
+	return fmt.Sprintf("%d pages", n)
+}
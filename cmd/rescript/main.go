@@ -2,265 +2,109 @@ package main
 
 import (
 	"fmt"
-	"io"
-	"io/ioutil"
 	"os"
-	"path/filepath"
 
 	"github.com/akeil/rmtool"
-	"github.com/akeil/rmtool/pkg/api"
-	"golang.org/x/sync/errgroup"
 	"gopkg.in/alecthomas/kingpin.v2"
-	"gopkg.in/yaml.v2"
-
-	"github.com/akeil/rescript"
-)
-
-const (
-	checkmark = "\u2713"
-	crossmark = "\u2717"
-	ellipsis  = "\u2026"
-	dstStdout = "-"
 )
 
-var langs = map[string]rescript.LanguageCode{
-	"en": rescript.LangEN,
-	"de": rescript.LangDE,
-}
+const dstStdout = "-"
 
 func main() {
-	app := kingpin.New("hwr", "reMarkable Handwriting Recogntion")
+	app := kingpin.New("rescript", "reMarkable Handwriting Recogntion")
 	app.HelpFlag.Short('h')
-
+	app.Flag("log-format", "Format for messages on STDERR").Default(logText).EnumVar(&logFormat, logText, logJSON)
+
+	// recognize is the default command so that "rescript NAME" still works
+	recognize := app.Command("recognize", "Convert notebooks to text").Default()
+	var rf recognizeFlags
+	recognize.Arg("name", "Name of the notebook to convert, matches a part of the name").StringVar(&rf.name)
+	recognize.Flag("output", "Directory for output document, \"-\" for STDOUT").Short('o').Default(".").StringVar(&rf.dst)
+	recognize.Flag("name-template", "Template for output file names, e.g. \"{{.Folder}}/{{.Name}}-{{.Modified}}\"").Default(defaultNameTemplate).StringVar(&rf.nameTemplate)
+	recognize.Flag("on-conflict", "What to do if an output file exists").Default(conflictOverwrite).EnumVar(&rf.onConflict, conflictOverwrite, conflictSkip, conflictSuffix)
+	recognize.Flag("dry-run", "Show the requests that would be sent to the MyScript API, but do not send them").BoolVar(&rf.dryRun)
+	recognize.Flag("combine", "Write all notebooks into one document").BoolVar(&rf.combine)
+	addConvertFlags(recognize, &rf.convertFlags)
+	addSelectFlags(recognize, &rf.selectFlags)
+
+	sync := app.Command("sync", "Convert all notebooks in a folder, keeping the folder structure")
+	var sf syncFlags
+	sync.Arg("folder", "Path of the folder on the tablet, e.g. \"Work/Meetings\"").Default("/").StringVar(&sf.folder)
+	sync.Flag("out", "Directory for output documents").Default(".").StringVar(&sf.out)
+	addConvertFlags(sync, &sf.convertFlags)
+
+	watch := app.Command("watch", "Keep converting new or modified notebooks in a folder")
+	var wf watchFlags
+	watch.Arg("folder", "Path of the folder on the tablet, e.g. \"Work/Meetings\"").Default("/").StringVar(&wf.folder)
+	watch.Flag("out", "Directory for output documents").Default(".").StringVar(&wf.out)
+	watch.Flag("interval", "Time between checks for changes").Default("5m").DurationVar(&wf.interval)
+	watch.Flag("local", "Read notebooks from a local copy of the xochitl directory instead of the cloud").ExistingDirVar(&wf.local)
+	addConvertFlags(watch, &wf.convertFlags)
+
+	ls := app.Command("ls", "Show the notebook tree with IDs and page counts")
 	var (
-		name   = app.Arg("name", "Name of the notebook to convert").Required().String()
-		dst    = app.Flag("output", "Directory for output document, \"-\" for STDOUT").Short('o').Default(".").String()
-		format = app.Flag("format", "Output format").Short('f').Default("txt").Enum("txt", "md")
-		lang   = app.Flag("lang", "Language of the notebook").Short('l').Default("en").String()
+		match = ls.Arg("match", "Name must match this").String()
 	)
 
-	kingpin.MustParse(app.Parse(os.Args[1:]))
-
-	rmtool.SetLogLevel("error")
-
-	err := run(*name, *dst, *lang, *format)
-	if err != nil {
-		message("%v Error: %v", crossmark, err)
-		os.Exit(1)
-	}
-
-	message("%v Done.", checkmark)
-}
-
-func run(name, dst, lang, format string) error {
-	lc, ok := langs[lang]
-	if !ok {
-		return fmt.Errorf("invalid language %q", lang)
-	}
-
-	s, err := loadSettings()
-	if err != nil {
-		return err
-	}
-
-	rec := rescript.NewRecognizer(s.AppKey, s.HmacKey, s.hwrCache())
-
-	c, err := initClient(s)
-	if err != nil {
-		return err
-	}
-
-	r := api.NewRepository(c, s.CacheDir)
-
-	items, err := r.List()
-	if err != nil {
-		return err
-	}
-	root := rmtool.BuildTree(items)
-	root = root.Filtered(rmtool.IsDocument, rmtool.MatchName(name))
-
-	cmp := selectComposer(format)
-
-	pipeline := rescript.BuildPipeline(rescript.Dehyphenate)
-
-	// do recognition for each matching document
-	var group errgroup.Group
-	root.Walk(func(n *rmtool.Node) error {
-		if n.Type() == rmtool.CollectionType {
-			return nil
-		}
-
-		group.Go(func() error {
-			message("%v download notebook %q", ellipsis, n.Name())
-			doc, err := rmtool.ReadDocument(r, n)
-			if err != nil {
-				return err
-			}
-
-			message("%v recognize handwriting (%v) for %q", ellipsis, lang, n.Name())
-			results, err := rec.Recognize(doc, lc)
-			if err != nil {
-				return err
-			}
-
-			for k, node := range results {
-				results[k] = pipeline(node)
-			}
-
-			var w io.Writer
-			var path string
-			if dst == dstStdout {
-				w = os.Stdout
-				path = "STDOUT"
-			} else {
-				path = filepath.Join(dst, doc.Name()+"."+format)
-				f, err := os.Create(path)
-				if err != nil {
-					return nil
-				}
-				defer f.Close()
-				w = f
-			}
-
-			m := rescript.Metadata{
-				Title: doc.Name(),
-				PageIDs: doc.Pages(),
-			}
-
-			err = cmp(w, m, results)
-			if err != nil {
-				return err
-			}
-			message("%v write result to %q", checkmark, path)
-			return nil
-		})
-		return nil
-	})
-	return group.Wait()
-}
-
-func initClient(s settings) (*api.Client, error) {
-	token, err := loadToken(s.tokenPath())
-	if err != nil {
-		return nil, err
-	}
-	client := api.DefaultClient(token)
-
-	err = register(s, client)
-	if err != nil {
-		return nil, err
-	}
-
-	return client, nil
-}
-
-func register(s settings, c *api.Client) error {
-	if c.IsRegistered() {
-		return nil
-	}
-
-	code, err := readInput("Enter one time code from https://my.remarkable.com/")
-	if err != nil {
-		return err
-	}
-
-	token, err := c.Register(code)
-	if err != nil {
-		return err
-	}
+	app.Command("login", "Register this tool with the reMarkable cloud (again)")
 
-	err = saveToken(s.tokenPath(), token)
-	if err != nil {
-		return err
-	}
+	cache := app.Command("cache", "Show the size of the caches")
+	var (
+		clear = cache.Flag("clear", "Delete downloaded notebooks and recognition results").Bool()
+	)
 
-	return nil
-}
+	usage := app.Command("usage", "Show the number of requests to the MyScript API")
+	var (
+		month = usage.Arg("month", "Month to show, e.g. \"2021-01\" (default: this month)").String()
+	)
 
-func readInput(msg string) (string, error) {
-	var reply string
+	config := app.Command("config", "Manage the configuration file")
+	config.Command("show", "Print the configuration")
+	config.Command("init", "Create a configuration file with default settings")
+	config.Command("validate", "Check the configuration for errors")
 
-	message("%v: ", msg)
-	_, err := fmt.Scanf("%s", &reply)
+	command := kingpin.MustParse(app.Parse(os.Args[1:]))
 
-	return reply, err
-}
+	rmtool.SetLogLevel("error")
 
-func selectComposer(t string) rescript.ComposeFunc {
-	switch t {
-	case "txt":
-		return rescript.NewPlaintextComposer()
-	case "md":
-		return rescript.NewMarkdownComposer()
+	// these commands work without (valid) settings
+	var err error
+	switch command {
+	case "config init":
+		err = doConfigInit()
+	case "config validate":
+		err = doConfigValidate()
 	default:
-		return rescript.NewPlaintextComposer()
-	}
-}
-
-func loadToken(path string) (string, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return "", err
-	}
-	defer f.Close()
-	d, err := ioutil.ReadAll(f)
-	if err != nil {
-		return "", err
-	}
-
-	return string(d), err
-}
-
-func saveToken(path, token string) error {
-	f, err := os.Create(path)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	_, err = f.Write([]byte(token))
-	return err
-}
-
-func message(s string, params ...interface{}) {
-	msg := fmt.Sprintf(s, params...)
-	msg = msg + "\n"
-	os.Stderr.WriteString(msg)
-}
-
-type settings struct {
-	DataDir  string
-	CacheDir string
-	AppKey   string
-	HmacKey  string
-}
-
-func (s settings) tokenPath() string {
-	return filepath.Join(s.DataDir, "device-token")
-}
-
-func (s settings) hwrCache() string {
-	return filepath.Join(s.CacheDir, "hwr")
-}
-
-func loadSettings() (settings, error) {
-	s := settings{}
-	config, err := os.UserConfigDir()
-	if err != nil {
-		return s, err
-	}
+		var s settings
+		s, err = loadSettings()
+		if err != nil {
+			break
+		}
 
-	path := filepath.Join(config, "rmhwr-conf.yaml")
-	f, err := os.Open(path)
-	if err != nil {
-		return s, err
+		switch command {
+		case "recognize":
+			err = doRecognize(s, rf)
+		case "sync":
+			err = doSync(s, sf)
+		case "watch":
+			err = doWatch(s, wf)
+		case "ls":
+			err = doLs(s, *match)
+		case "login":
+			err = doLogin(s)
+		case "cache":
+			err = doCache(s, *clear)
+		case "usage":
+			err = doUsage(s, *month)
+		case "config show":
+			err = doConfigShow(s)
+		default:
+			err = fmt.Errorf("unknown command: %q", command)
+		}
 	}
-	defer f.Close()
 
-	err = yaml.NewDecoder(f).Decode(&s)
 	if err != nil {
-		return s, err
+		failure(err)
+		os.Exit(1)
 	}
-
-	return s, nil
 }
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/akeil/rmtool"
+)
+
+// defaultNameTemplate names output files like the notebook.
+const defaultNameTemplate = "{{.Name}}"
+
+// Policies for output files that already exist.
+const (
+	conflictOverwrite = "overwrite"
+	conflictSkip      = "skip"
+	conflictSuffix    = "suffix"
+)
+
+// A namer creates the paths for output files from a template.
+//
+// Notebooks from the same run never overwrite each other; if two notebooks
+// get the same name, a number is added to the second one.
+// The policy decides what happens with files that already exist.
+type namer struct {
+	tmpl    *template.Template
+	dst     string
+	ext     string
+	policy  string
+	claimed map[string]bool
+}
+
+// nameData holds the fields that can be used in a name template.
+type nameData struct {
+	Name string
+	// Folder is the path of the parent folder, e.g. "Work/Meetings".
+	Folder string
+	ID     string
+	// Modified is the date of the last change, e.g. "2021-01-31".
+	Modified string
+	Version  uint
+}
+
+func newNamer(text, policy, dst, ext string) (*namer, error) {
+	tmpl, err := template.New("name").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid name template: %v", err)
+	}
+
+	return &namer{
+		tmpl:    tmpl,
+		dst:     dst,
+		ext:     ext,
+		policy:  policy,
+		claimed: make(map[string]bool),
+	}, nil
+}
+
+// path returns the path of the output file for a notebook.
+// It returns an empty path if the notebook should be skipped.
+func (nm *namer) path(n *rmtool.Node) (string, error) {
+	folder := make([]string, 0)
+	for _, name := range folderPath(n) {
+		folder = append(folder, sanitizeName(name))
+	}
+
+	data := nameData{
+		Name:     sanitizeName(n.Name()),
+		Folder:   strings.Join(folder, "/"),
+		ID:       n.ID(),
+		Modified: n.LastModified().Local().Format("2006-01-02"),
+		Version:  n.Version(),
+	}
+
+	var b bytes.Buffer
+	err := nm.tmpl.Execute(&b, data)
+	if err != nil {
+		return "", fmt.Errorf("invalid name template: %v", err)
+	}
+
+	// slashes from the template create subdirectories
+	parts := make([]string, 0)
+	for _, part := range strings.Split(b.String(), "/") {
+		if strings.TrimSpace(part) != "" {
+			parts = append(parts, sanitizeName(part))
+		}
+	}
+	if len(parts) == 0 {
+		parts = append(parts, data.Name)
+	}
+	base := filepath.Join(append([]string{nm.dst}, parts...)...)
+
+	p := base + "." + nm.ext
+	for i := 2; nm.claimed[p] || (nm.policy == conflictSuffix && exists(p)); i++ {
+		p = fmt.Sprintf("%v (%d).%v", base, i, nm.ext)
+	}
+	// a skipped notebook keeps its name,
+	// so that other notebooks get the same names as in a full run
+	nm.claimed[p] = true
+	if nm.policy == conflictSkip && exists(p) {
+		success("skip %q, %q exists", n.Name(), p)
+		return "", nil
+	}
+	return p, nil
+}
+
+// sanitizeName makes a notebook or folder name safe to use as a file name.
+//
+// Path separators and characters that are not allowed on common file
+// systems are replaced.
+func sanitizeName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '-'
+		}
+		return r
+	}, s)
+
+	// no hidden files, no ".." and no trailing dots or spaces (for Windows)
+	s = strings.Trim(s, ". ")
+	if s == "" {
+		return "untitled"
+	}
+	return s
+}
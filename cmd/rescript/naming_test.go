@@ -0,0 +1,145 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/akeil/rmtool"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeName(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := map[string]string{
+		"Notes":            "Notes",
+		"a/b":              "a-b",
+		`a\b:c*d?e"f<g>h|`: "a-b-c-d-e-f-g-h-",
+		"tab\there":        "tab-here",
+		"..":               "untitled",
+		"":                 "untitled",
+		" . ":              "untitled",
+		".hidden":          "hidden",
+		"trailing. ":       "trailing",
+		"Ünïcödé ✓":        "Ünïcödé ✓",
+	}
+	for in, expected := range cases {
+		assert.Equal(expected, sanitizeName(in), in)
+	}
+}
+
+func TestNamerPath(t *testing.T) {
+	assert := assert.New(t)
+
+	dst, err := ioutil.TempDir("", "rescript-test")
+	assert.Nil(err)
+	defer os.RemoveAll(dst)
+
+	modified := time.Date(2021, 1, 31, 12, 0, 0, 0, time.Local)
+	root := testTree(
+		&localMeta{id: "work", VisibleName: "Work", Kind: rmtool.CollectionType},
+		&localMeta{id: "sub", VisibleName: "a/b", Kind: rmtool.CollectionType, ParentID: "work"},
+		&localMeta{id: "nb1", VisibleName: "Notes", Kind: rmtool.DocumentType, ParentID: "sub", Ver: 3, lastModified: modified},
+		&localMeta{id: "nb2", VisibleName: "Notes", Kind: rmtool.DocumentType, ParentID: "work"},
+		&localMeta{id: "nb3", VisibleName: "..", Kind: rmtool.DocumentType},
+	)
+	nb1 := findNode(root, "nb1")
+
+	cases := []struct {
+		tmpl     string
+		expected string
+	}{
+		{defaultNameTemplate, "Notes.md"},
+		{"{{.Folder}}/{{.Name}}", "Work/a-b/Notes.md"},
+		{"{{.Name}}-{{.Modified}}-v{{.Version}}", "Notes-2021-01-31-v3.md"},
+		{"{{.ID}}", "nb1.md"},
+		// no way out of the output directory
+		{"../{{.Name}}", "untitled/Notes.md"},
+		{"//{{.Name}}//", "Notes.md"},
+		// an empty name falls back to the notebook name
+		{"{{if false}}x{{end}}", "Notes.md"},
+	}
+	for _, c := range cases {
+		nm, err := newNamer(c.tmpl, conflictOverwrite, dst, "md")
+		assert.Nil(err, c.tmpl)
+		p, err := nm.path(nb1)
+		assert.Nil(err, c.tmpl)
+		assert.Equal(filepath.Join(dst, c.expected), p, c.tmpl)
+	}
+
+	for _, tmpl := range []string{"{{.Name", "{{.Unknown}}"} {
+		nm, err := newNamer(tmpl, conflictOverwrite, dst, "md")
+		if err == nil {
+			_, err = nm.path(nb1)
+		}
+		assert.Error(err, tmpl)
+	}
+
+	nm, err := newNamer(defaultNameTemplate, conflictOverwrite, dst, "md")
+	assert.Nil(err)
+	p, err := nm.path(findNode(root, "nb3"))
+	assert.Nil(err)
+	assert.Equal(filepath.Join(dst, "untitled.md"), p)
+}
+
+func TestNamerConflicts(t *testing.T) {
+	assert := assert.New(t)
+
+	dst, err := ioutil.TempDir("", "rescript-test")
+	assert.Nil(err)
+	defer os.RemoveAll(dst)
+	assert.Nil(ioutil.WriteFile(filepath.Join(dst, "Notes.md"), []byte("x"), 0644))
+
+	root := testTree(
+		&localMeta{id: "nb1", VisibleName: "Notes", Kind: rmtool.DocumentType},
+		&localMeta{id: "nb2", VisibleName: "Notes", Kind: rmtool.DocumentType},
+		&localMeta{id: "nb3", VisibleName: "Other", Kind: rmtool.DocumentType},
+	)
+
+	cases := []struct {
+		policy   string
+		expected []string
+	}{
+		// notebooks from the same run never overwrite each other
+		{conflictOverwrite, []string{"Notes.md", "Notes (2).md", "Other.md"}},
+		{conflictSuffix, []string{"Notes (2).md", "Notes (3).md", "Other.md"}},
+		{conflictSkip, []string{"", "Notes (2).md", "Other.md"}},
+	}
+	for _, c := range cases {
+		nm, err := newNamer(defaultNameTemplate, c.policy, dst, "md")
+		assert.Nil(err)
+		for i, id := range []string{"nb1", "nb2", "nb3"} {
+			p, err := nm.path(findNode(root, id))
+			assert.Nil(err)
+			expected := ""
+			if c.expected[i] != "" {
+				expected = filepath.Join(dst, c.expected[i])
+			}
+			assert.Equal(expected, p, c.policy+" "+id)
+		}
+	}
+}
+
+// testTree builds a notebook tree from the given items.
+func testTree(items ...*localMeta) *rmtool.Node {
+	meta := make([]rmtool.Meta, len(items))
+	for i, m := range items {
+		meta[i] = m
+	}
+	return rmtool.BuildTree(meta)
+}
+
+// findNode looks up a node by ID.
+func findNode(root *rmtool.Node, id string) *rmtool.Node {
+	var found *rmtool.Node
+	root.Walk(func(n *rmtool.Node) error {
+		if n.ID() == id {
+			found = n
+		}
+		return nil
+	})
+	return found
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A pageRange is a range of page numbers, including both ends.
+// If to is zero, the range extends to the last page.
+type pageRange struct {
+	from int
+	to   int
+}
+
+// parsePages parses a list of page numbers and ranges like "3-5,9,12-".
+func parsePages(s string) ([]pageRange, error) {
+	ranges := make([]pageRange, 0)
+	if strings.TrimSpace(s) == "" {
+		return ranges, nil
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		invalid := fmt.Errorf("invalid page range %q, use e.g. \"3-5,9\"", part)
+
+		var r pageRange
+		var err error
+		if i := strings.Index(part, "-"); i != -1 {
+			r.from, err = strconv.Atoi(part[:i])
+			if err != nil {
+				return nil, invalid
+			}
+			if part[i+1:] != "" {
+				r.to, err = strconv.Atoi(part[i+1:])
+				if err != nil || r.to < r.from {
+					return nil, invalid
+				}
+			}
+		} else {
+			r.from, err = strconv.Atoi(part)
+			if err != nil {
+				return nil, invalid
+			}
+			r.to = r.from
+		}
+
+		if r.from < 1 {
+			return nil, invalid
+		}
+		ranges = append(ranges, r)
+	}
+
+	return ranges, nil
+}
+
+// pageNumbers returns the sorted numbers of the selected pages for a
+// document with count pages. If last is greater than zero, the last pages are
+// selected as well.
+func pageNumbers(ranges []pageRange, last, count int) []int {
+	selected := make(map[int]bool)
+	for _, r := range ranges {
+		to := r.to
+		if to == 0 || to > count {
+			to = count
+		}
+		for n := r.from; n <= to; n++ {
+			selected[n] = true
+		}
+	}
+	for n := count - last + 1; n <= count; n++ {
+		if n > 0 {
+			selected[n] = true
+		}
+	}
+
+	numbers := make([]int, 0, len(selected))
+	for n := range selected {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+	return numbers
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePages(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := []struct {
+		s        string
+		expected []pageRange
+	}{
+		{"", []pageRange{}},
+		{"  ", []pageRange{}},
+		{"3", []pageRange{{3, 3}}},
+		{"3-5,9", []pageRange{{3, 5}, {9, 9}}},
+		{" 2 , 4-4 ", []pageRange{{2, 2}, {4, 4}}},
+		{"12-", []pageRange{{12, 0}}},
+		{"1-2,2-3", []pageRange{{1, 2}, {2, 3}}},
+	}
+	for _, c := range cases {
+		ranges, err := parsePages(c.s)
+		assert.Nil(err, c.s)
+		assert.Equal(c.expected, ranges, c.s)
+	}
+
+	for _, s := range []string{"0", "0-3", "5-3", "a", "3-x", "-3", "1,,2", "1-2-3", "-"} {
+		_, err := parsePages(s)
+		assert.Error(err, s)
+	}
+}
+
+func TestPageNumbers(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := []struct {
+		name     string
+		ranges   []pageRange
+		last     int
+		count    int
+		expected []int
+	}{
+		{"nothing selected", nil, 0, 5, []int{}},
+		{"range", []pageRange{{2, 3}}, 0, 5, []int{2, 3}},
+		{"open range", []pageRange{{4, 0}}, 0, 6, []int{4, 5, 6}},
+		{"range beyond the end", []pageRange{{4, 10}}, 0, 5, []int{4, 5}},
+		{"pages beyond the end", []pageRange{{7, 7}, {9, 0}}, 0, 5, []int{}},
+		{"unordered with duplicates", []pageRange{{5, 5}, {1, 2}, {2, 3}}, 0, 5, []int{1, 2, 3, 5}},
+		{"last", nil, 2, 5, []int{4, 5}},
+		{"last overlaps range", []pageRange{{1, 4}}, 2, 5, []int{1, 2, 3, 4, 5}},
+		{"last exceeds count", nil, 10, 3, []int{1, 2, 3}},
+		{"empty document", []pageRange{{1, 0}}, 2, 0, []int{}},
+	}
+	for _, c := range cases {
+		assert.Equal(c.expected, pageNumbers(c.ranges, c.last, c.count), c.name)
+	}
+}
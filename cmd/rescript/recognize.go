@@ -0,0 +1,462 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/akeil/rmtool"
+	"github.com/akeil/rmtool/pkg/render"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/akeil/rescript"
+)
+
+var langs = map[string]rescript.LanguageCode{
+	"en": rescript.LangEN,
+	"de": rescript.LangDE,
+}
+
+// recognizeFlags holds the command line options for the recognize command.
+type recognizeFlags struct {
+	convertFlags
+	selectFlags
+	dst          string
+	nameTemplate string
+	onConflict   string
+	combine      bool
+	dryRun       bool
+}
+
+// recordSeparator precedes each document if several documents are written
+// to STDOUT. For JSON, this is a JSON text sequence (RFC 7464).
+const recordSeparator = "\x1e"
+
+func doRecognize(s settings, f recognizeFlags) error {
+	err := checkCombine(f)
+	if err != nil {
+		return err
+	}
+
+	r, err := setupRepo(s)
+	if err != nil {
+		return err
+	}
+
+	j, err := newJob(s, f.convertFlags, r)
+	if err != nil {
+		return err
+	}
+	j.requireTags = len(f.tags) != 0
+
+	items, err := r.List()
+	if err != nil {
+		return err
+	}
+	// a dry run is harmless, do not ask for confirmation
+	sf := f.selectFlags
+	sf.yes = sf.yes || f.dryRun
+	root, _, err := selectNotebooks(r, rmtool.BuildTree(items), sf)
+	if err != nil {
+		return err
+	}
+	nodes := sortedNotebooks(root)
+
+	if f.dryRun {
+		return dryRun(j, nodes)
+	}
+
+	// a single notebook gives its name to a combined document
+	title := f.name
+	if len(nodes) == 1 {
+		title = nodes[0].Name()
+	} else if title == "" {
+		title = "Notebooks"
+	}
+
+	switch {
+	case f.format == "epub" && f.template == "":
+		// EPUB output always combines all notebooks into one book
+		err = runBook(j, nodes, rescript.NewBook(title, j.copts...), f.dst)
+	case f.combine:
+		err = runCombined(j, nodes, title, f.dst)
+	default:
+		var nm *namer
+		nm, err = newNamer(f.nameTemplate, f.onConflict, f.dst, j.extension())
+		if err != nil {
+			return err
+		}
+		err = run(j, nodes, nm)
+	}
+	if err != nil {
+		return err
+	}
+
+	success("Done.")
+	return nil
+}
+
+// checkCombine tells if the output can be combined into one document.
+func checkCombine(f recognizeFlags) error {
+	if !f.combine || f.template != "" {
+		return nil
+	}
+	switch f.format {
+	case "txt", "md", "json", "epub":
+	default:
+		return fmt.Errorf("cannot combine %v documents, use txt, md, json or epub", f.format)
+	}
+	if f.frontMatter {
+		return fmt.Errorf("cannot combine documents with front matter")
+	}
+	return nil
+}
+
+// sortedNotebooks returns the notebooks from the tree, in the same order
+// as the chapters of an e-book (see rescript.DocumentLess).
+func sortedNotebooks(root *rmtool.Node) []*rmtool.Node {
+	nodes := make([]*rmtool.Node, 0)
+	root.Walk(func(n *rmtool.Node) error {
+		if rmtool.IsDocument(n) {
+			nodes = append(nodes, n)
+		}
+		return nil
+	})
+
+	meta := func(n *rmtool.Node) rescript.Metadata {
+		return rescript.Metadata{ID: n.ID(), Title: n.Name(), Path: folderPath(n)}
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return rescript.DocumentLess(meta(nodes[i]), meta(nodes[j]))
+	})
+	return nodes
+}
+
+// A notebook holds the recognition results for one notebook.
+type notebook struct {
+	m       rescript.Metadata
+	results map[string]*rescript.Node
+}
+
+// recognizeAll recognizes the notebooks concurrently
+// and returns the results in the same order.
+func recognizeAll(j *job, nodes []*rmtool.Node) ([]notebook, error) {
+	notebooks := make([]notebook, len(nodes))
+	var group errgroup.Group
+	for i, n := range nodes {
+		i, n := i, n
+		group.Go(func() error {
+			m, results, err := j.recognize(n)
+			if err != nil {
+				return err
+			}
+			notebooks[i] = notebook{m: m, results: results}
+			return nil
+		})
+	}
+
+	err := group.Wait()
+	return notebooks, err
+}
+
+// run converts each notebook into a separate document.
+func run(j *job, nodes []*rmtool.Node, nm *namer) error {
+	if nm.dst == dstStdout {
+		return writeStdout(j, nodes)
+	}
+
+	// decide on the file names first, in order, and skip existing files
+	// before recognition
+	paths := make([]string, len(nodes))
+	for i, n := range nodes {
+		var err error
+		paths[i], err = nm.path(n)
+		if err != nil {
+			return err
+		}
+	}
+
+	var group errgroup.Group
+	for i, n := range nodes {
+		n, path := n, paths[i]
+		if path == "" {
+			continue
+		}
+		group.Go(func() error {
+			return j.convert(n, path)
+		})
+	}
+	return group.Wait()
+}
+
+// writeStdout writes the documents to STDOUT, one after the other.
+//
+// If there are several documents, each one starts with an ASCII record
+// separator, so that the output can be split.
+func writeStdout(j *job, nodes []*rmtool.Node) error {
+	if len(nodes) > 1 && binaryFormat(j.flags.format) && j.flags.template == "" {
+		return fmt.Errorf("cannot write %d %v documents to STDOUT, use an output directory", len(nodes), j.flags.format)
+	}
+
+	notebooks, err := recognizeAll(j, nodes)
+	if err != nil {
+		return err
+	}
+	return writeRecords(j, os.Stdout, notebooks)
+}
+
+// writeRecords writes the documents one after the other, with a record
+// separator before each document if there are several.
+func writeRecords(j *job, w io.Writer, notebooks []notebook) error {
+	for _, nb := range notebooks {
+		if len(notebooks) > 1 {
+			_, err := io.WriteString(w, recordSeparator)
+			if err != nil {
+				return err
+			}
+		}
+		err := j.compose(w, nb.m, nb.results)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runCombined converts all notebooks into one document.
+func runCombined(j *job, nodes []*rmtool.Node, title, dst string) error {
+	notebooks, err := recognizeAll(j, nodes)
+	if err != nil {
+		return err
+	}
+
+	write := func(w io.Writer) error {
+		return combine(j, w, notebooks)
+	}
+	if dst == dstStdout {
+		return write(os.Stdout)
+	}
+
+	path := filepath.Join(dst, sanitizeName(title)+"."+j.extension())
+	err = writeFile(path, write)
+	if err != nil {
+		return err
+	}
+	success("write %d notebooks to %q", len(notebooks), path)
+	return nil
+}
+
+// combine writes the notebooks into one document.
+//
+// Documents are concatenated, each one starts with the title of its notebook.
+// JSON documents are combined into an array.
+func combine(j *job, w io.Writer, notebooks []notebook) error {
+	isJSON := j.flags.format == "json" && j.flags.template == ""
+
+	var b bytes.Buffer
+	if isJSON {
+		b.WriteString("[\n")
+	}
+	for i, nb := range notebooks {
+		if i != 0 {
+			if isJSON {
+				b.WriteString(",")
+			}
+			b.WriteString("\n")
+		}
+
+		var doc bytes.Buffer
+		err := j.compose(&doc, nb.m, nb.results)
+		if err != nil {
+			return err
+		}
+		b.Write(bytes.TrimRight(doc.Bytes(), "\n"))
+	}
+	if isJSON {
+		b.WriteString("\n]")
+	}
+	b.WriteString("\n")
+
+	_, err := b.WriteTo(w)
+	return err
+}
+
+// runBook converts all notebooks into chapters of an e-book.
+func runBook(j *job, nodes []*rmtool.Node, book *rescript.Book, dst string) error {
+	notebooks, err := recognizeAll(j, nodes)
+	if err != nil {
+		return err
+	}
+	for _, nb := range notebooks {
+		book.Add(nb.m, nb.results)
+	}
+
+	if dst == dstStdout {
+		return book.Write(os.Stdout)
+	}
+
+	path := filepath.Join(dst, sanitizeName(book.Title)+".epub")
+	err = writeFile(path, book.Write)
+	if err != nil {
+		return err
+	}
+	success("write %d notebooks to %q", book.Len(), path)
+	return nil
+}
+
+// binaryFormat tells if documents in the given format cannot be
+// concatenated.
+func binaryFormat(format string) bool {
+	switch format {
+	case "pdf", "epub", "docx", "odt":
+		return true
+	default:
+		return false
+	}
+}
+
+func selectComposer(s settings, t, css string, opts ...rescript.ComposeOption) (rescript.ComposeFunc, error) {
+	switch t {
+	case "txt":
+		return rescript.NewPlaintextComposer(opts...), nil
+	case "md":
+		return rescript.NewMarkdownComposer(opts...), nil
+	case "org":
+		return rescript.NewOrgComposer(opts...), nil
+	case "adoc":
+		return rescript.NewAsciiDocComposer(opts...), nil
+	case "html":
+		var style string
+		if css != "" {
+			data, err := ioutil.ReadFile(css)
+			if err != nil {
+				return nil, err
+			}
+			style = string(data)
+		}
+		return rescript.NewHTMLComposer(style, opts...), nil
+	case "pdf":
+		c := render.DefaultContext()
+		if s.RenderDir != "" {
+			c.DataDir = s.RenderDir
+		}
+		return rescript.NewPDFComposer(c, opts...), nil
+	case "hocr":
+		return rescript.NewHOCRComposer(opts...), nil
+	case "alto":
+		return rescript.NewALTOComposer(opts...), nil
+	case "json":
+		return rescript.NewJSONComposer(opts...), nil
+	case "epub":
+		return rescript.NewEPUBComposer(opts...), nil
+	case "docx":
+		return rescript.NewDOCXComposer(opts...), nil
+	case "odt":
+		return rescript.NewODTComposer(opts...), nil
+	default:
+		return rescript.NewPlaintextComposer(opts...), nil
+	}
+}
+
+// folderPath returns the names of the parent folders for a node,
+// without the root folder.
+func folderPath(n *rmtool.Node) []string {
+	p := n.Path()
+	if len(p) != 0 {
+		return p[1:]
+	}
+	return p
+}
+
+// builtinTemplates can be used with --template instead of a file.
+var builtinTemplates = map[string]string{
+	"plaintext": rescript.PlaintextTemplate,
+	"markdown":  rescript.MarkdownTemplate,
+}
+
+// templateComposer creates a composer from the template in the given file
+// or from a built-in template with the given name.
+func templateComposer(path string, opts ...rescript.ComposeOption) (rescript.ComposeFunc, error) {
+	text, builtin := builtinTemplates[path]
+	if !builtin || exists(path) {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		text = string(data)
+	}
+	return rescript.NewTemplateComposer(text, opts...)
+}
+
+// newConverter sets up the converter with the stroke filters from the settings
+// and an optional crop region.
+func newConverter(s settings, crop string) (*rescript.Converter, error) {
+	conv := rescript.NewConverter()
+	if s.Timing.SpeedFactor > 0 {
+		conv.SpeedFactor = s.Timing.SpeedFactor
+	}
+	if s.Timing.MinSpeed > 0 {
+		conv.MinSpeed = s.Timing.MinSpeed
+	}
+	if s.Timing.StrokeGap > 0 {
+		conv.StrokeGap = s.Timing.StrokeGap
+	}
+
+	for _, rule := range s.Strokes {
+		f, err := rule.Filter()
+		if err != nil {
+			return nil, err
+		}
+		conv.Filters = append(conv.Filters, f)
+	}
+
+	if crop != "" {
+		region, err := parseRegion(crop)
+		if err != nil {
+			return nil, err
+		}
+		conv.Transform.Crop = region
+	}
+
+	return conv, nil
+}
+
+// parseRegion parses a region from a string like "X,Y,WIDTH,HEIGHT".
+func parseRegion(s string) (rescript.Region, error) {
+	var r rescript.Region
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return r, fmt.Errorf("invalid region %q, expected X,Y,WIDTH,HEIGHT", s)
+	}
+
+	values := make([]float64, len(parts))
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return r, fmt.Errorf("invalid region %q: %v", s, err)
+		}
+		values[i] = v
+	}
+
+	r.X, r.Y, r.Width, r.Height = values[0], values[1], values[2], values[3]
+	if r.Width <= 0 || r.Height <= 0 {
+		return r, fmt.Errorf("invalid region %q, width and height must be positive", s)
+	}
+	return r, nil
+}
+
+// fileExtension returns the extension for output files with the given format.
+func fileExtension(format string) string {
+	switch format {
+	case "alto":
+		return "xml"
+	default:
+		return format
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/akeil/rmtool"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/akeil/rescript"
+)
+
+func TestWriteRecords(t *testing.T) {
+	assert := assert.New(t)
+
+	j := &job{flags: convertFlags{format: "txt"}, compose: rescript.NewPlaintextComposer()}
+	one := sampleNotebook("One", "first")
+	two := sampleNotebook("Two", "second")
+
+	var buf bytes.Buffer
+	err := writeRecords(j, &buf, []notebook{one, two})
+	assert.Nil(err)
+	records := strings.Split(buf.String(), recordSeparator)
+	assert.Equal(3, len(records))
+	assert.Equal("", records[0])
+	assert.True(strings.HasPrefix(records[1], "ONE\n"), records[1])
+	assert.Contains(records[1], "first")
+	assert.True(strings.HasPrefix(records[2], "TWO\n"), records[2])
+
+	// no separator for a single document
+	buf.Reset()
+	err = writeRecords(j, &buf, []notebook{one})
+	assert.Nil(err)
+	assert.NotContains(buf.String(), recordSeparator)
+	assert.True(strings.HasPrefix(buf.String(), "ONE\n"))
+
+	// each record is a JSON text
+	j = &job{flags: convertFlags{format: "json"}, compose: rescript.NewJSONComposer()}
+	buf.Reset()
+	err = writeRecords(j, &buf, []notebook{one, two})
+	assert.Nil(err)
+	records = strings.Split(buf.String(), recordSeparator)
+	assert.Equal(3, len(records))
+	for i, title := range []string{"One", "Two"} {
+		var doc struct{ Title string }
+		assert.Nil(json.Unmarshal([]byte(records[i+1]), &doc))
+		assert.Equal(title, doc.Title)
+	}
+}
+
+func TestWriteStdoutBinary(t *testing.T) {
+	assert := assert.New(t)
+
+	root := testTree(
+		&localMeta{id: "nb1", VisibleName: "One", Kind: rmtool.DocumentType},
+		&localMeta{id: "nb2", VisibleName: "Two", Kind: rmtool.DocumentType},
+	)
+	nodes := sortedNotebooks(root)
+
+	for _, format := range []string{"pdf", "epub", "docx", "odt"} {
+		j := &job{flags: convertFlags{format: format}}
+		err := writeStdout(j, nodes)
+		assert.Error(err, format)
+	}
+}
+
+func TestCombine(t *testing.T) {
+	assert := assert.New(t)
+
+	one := sampleNotebook("One", "first")
+	two := sampleNotebook("Two", "second")
+
+	j := &job{flags: convertFlags{format: "md"}, compose: rescript.NewMarkdownComposer()}
+	var buf bytes.Buffer
+	err := combine(j, &buf, []notebook{one, two})
+	assert.Nil(err)
+	s := buf.String()
+	assert.NotContains(s, recordSeparator)
+	assert.True(strings.HasPrefix(s, "# One\n"), s)
+	assert.Contains(s, "\n# Two\n")
+	assert.True(strings.Index(s, "first") < strings.Index(s, "# Two"))
+	assert.True(strings.HasSuffix(s, "second\n"), s)
+
+	// JSON documents are combined into an array
+	j = &job{flags: convertFlags{format: "json"}, compose: rescript.NewJSONComposer()}
+	for _, notebooks := range [][]notebook{{one, two}, {one}} {
+		buf.Reset()
+		err = combine(j, &buf, notebooks)
+		assert.Nil(err)
+		assert.NotContains(buf.String(), recordSeparator)
+
+		var docs []struct{ Title string }
+		assert.Nil(json.Unmarshal(buf.Bytes(), &docs), buf.String())
+		assert.Equal(len(notebooks), len(docs))
+		for i, nb := range notebooks {
+			assert.Equal(nb.m.Title, docs[i].Title)
+		}
+	}
+}
+
+func TestCheckCombine(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := []struct {
+		name  string
+		flags recognizeFlags
+		ok    bool
+	}{
+		{"no combine", recognizeFlags{convertFlags: convertFlags{format: "pdf"}}, true},
+		{"txt", recognizeFlags{convertFlags: convertFlags{format: "txt"}, combine: true}, true},
+		{"md", recognizeFlags{convertFlags: convertFlags{format: "md"}, combine: true}, true},
+		{"json", recognizeFlags{convertFlags: convertFlags{format: "json"}, combine: true}, true},
+		{"epub", recognizeFlags{convertFlags: convertFlags{format: "epub"}, combine: true}, true},
+		{"template", recognizeFlags{convertFlags: convertFlags{format: "pdf", template: "markdown"}, combine: true}, true},
+		{"html", recognizeFlags{convertFlags: convertFlags{format: "html"}, combine: true}, false},
+		{"pdf", recognizeFlags{convertFlags: convertFlags{format: "pdf"}, combine: true}, false},
+		{"docx", recognizeFlags{convertFlags: convertFlags{format: "docx"}, combine: true}, false},
+		{"org", recognizeFlags{convertFlags: convertFlags{format: "org"}, combine: true}, false},
+		{"front matter", recognizeFlags{convertFlags: convertFlags{format: "md", frontMatter: true}, combine: true}, false},
+	}
+	for _, c := range cases {
+		err := checkCombine(c.flags)
+		if c.ok {
+			assert.Nil(err, c.name)
+		} else {
+			assert.Error(err, c.name)
+		}
+	}
+}
+
+func TestSortedNotebooks(t *testing.T) {
+	assert := assert.New(t)
+
+	root := testTree(
+		&localMeta{id: "work", VisibleName: "work", Kind: rmtool.CollectionType},
+		&localMeta{id: "archive", VisibleName: "Archive", Kind: rmtool.CollectionType},
+		&localMeta{id: "nb1", VisibleName: "Notes", Kind: rmtool.DocumentType, ParentID: "work"},
+		&localMeta{id: "nb2", VisibleName: "agenda", Kind: rmtool.DocumentType, ParentID: "work"},
+		&localMeta{id: "nb3", VisibleName: "Zebra", Kind: rmtool.DocumentType},
+		&localMeta{id: "nb4", VisibleName: "Old", Kind: rmtool.DocumentType, ParentID: "archive"},
+		&localMeta{id: "nb6", VisibleName: "Notes", Kind: rmtool.DocumentType, ParentID: "work"},
+		&localMeta{id: "nb5", VisibleName: "notes", Kind: rmtool.DocumentType, ParentID: "work"},
+		&localMeta{id: "nb7", VisibleName: "apple", Kind: rmtool.DocumentType},
+	)
+
+	ids := make([]string, 0)
+	for _, n := range sortedNotebooks(root) {
+		ids = append(ids, n.ID())
+	}
+	// the same order as the chapters of an e-book: notebooks in a folder
+	// first, then subfolders; by name (case insensitive), then ID
+	assert.Equal([]string{"nb7", "nb3", "nb4", "nb2", "nb1", "nb6", "nb5"}, ids)
+}
+
+// sampleNotebook creates recognition results with one page of words.
+func sampleNotebook(title string, words ...string) notebook {
+	var tail, head *rescript.Node
+	for i, w := range words {
+		if i != 0 {
+			n := rescript.NewNode(rescript.NewToken(" "))
+			head.InsertAfter(n)
+			head = n
+		}
+		n := rescript.NewNode(rescript.NewToken(w))
+		if head != nil {
+			head.InsertAfter(n)
+		} else {
+			tail = n
+		}
+		head = n
+	}
+
+	return notebook{
+		m: rescript.Metadata{
+			ID:      strings.ToLower(title),
+			Title:   title,
+			PageIDs: []string{"page0"},
+		},
+		results: map[string]*rescript.Node{"page0": tail},
+	}
+}
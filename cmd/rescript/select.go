@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/akeil/rmtool"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// confirmAbove is the number of notebooks that can be converted without
+// asking for confirmation.
+const confirmAbove = 10
+
+// selectFlags holds the command line options that select notebooks.
+type selectFlags struct {
+	name          string
+	ids           []string
+	exact         bool
+	path          string
+	modifiedSince string
+	tags          []string
+	yes           bool
+}
+
+// addSelectFlags adds the flags for selecting notebooks to the given command.
+func addSelectFlags(cmd *kingpin.CmdClause, f *selectFlags) {
+	cmd.Flag("id", "ID of a notebook to convert").StringsVar(&f.ids)
+	cmd.Flag("exact", "The name must match exactly instead of a part of the name").BoolVar(&f.exact)
+	cmd.Flag("path", "Folder and name must match this pattern, e.g. \"Work/Meetings/*\"").StringVar(&f.path)
+	cmd.Flag("modified-since", "Only notebooks modified after a date (\"2006-01-02\") or within a duration (\"36h\", \"7d\")").StringVar(&f.modifiedSince)
+	cmd.Flag("tag", "Only notebooks with this tag").StringsVar(&f.tags)
+	cmd.Flag("yes", fmt.Sprintf("Do not ask for confirmation if more than %d notebooks match", confirmAbove)).Short('y').BoolVar(&f.yes)
+}
+
+// selectNotebooks filters the tree of notebooks and returns the filtered tree
+// and the number of selected notebooks.
+//
+// If many notebooks are selected, the user is asked to confirm.
+func selectNotebooks(repo rmtool.Repository, root *rmtool.Node, f selectFlags) (*rmtool.Node, int, error) {
+	filters, err := f.filters(time.Now())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// tags are checked last because the notebook must be downloaded
+	var tagErr error
+	if len(f.tags) != 0 {
+		filters = append(filters, func(n *rmtool.Node) bool {
+			if tagErr != nil {
+				return false
+			}
+			tags, err := readTags(repo, n)
+			if err != nil {
+				tagErr = err
+				return false
+			}
+			return hasTags(tags, f.tags)
+		})
+	}
+
+	root = root.Filtered(filters...)
+	if tagErr != nil {
+		return nil, 0, tagErr
+	}
+
+	count := 0
+	root.Walk(func(n *rmtool.Node) error {
+		if rmtool.IsDocument(n) {
+			count++
+		}
+		return nil
+	})
+
+	if count == 0 {
+		return nil, 0, fmt.Errorf("no matching notebooks")
+	}
+	if count > confirmAbove && !f.yes {
+		reply, err := readInput(fmt.Sprintf("%d notebooks match, convert all of them? [y/N]", count))
+		if err != nil || !isYes(reply) {
+			return nil, 0, fmt.Errorf("canceled, use more specific selectors or --yes")
+		}
+	}
+
+	return root, count, nil
+}
+
+// filters creates the node filters for the selectors,
+// except for tags.
+func (f selectFlags) filters(now time.Time) ([]rmtool.NodeFilter, error) {
+	if f.name == "" && len(f.ids) == 0 && f.path == "" && f.modifiedSince == "" && len(f.tags) == 0 {
+		return nil, fmt.Errorf("no notebooks selected, give a name or use --id, --path, --tag or --modified-since")
+	}
+	if f.exact && f.name == "" {
+		return nil, fmt.Errorf("--exact requires a name")
+	}
+
+	filters := []rmtool.NodeFilter{rmtool.IsDocument, notInTrash}
+
+	if f.name != "" {
+		if f.exact {
+			filters = append(filters, matchExact(f.name))
+		} else {
+			filters = append(filters, rmtool.MatchName(f.name))
+		}
+	}
+
+	if len(f.ids) != 0 {
+		filters = append(filters, matchIDs(f.ids))
+	}
+
+	if f.path != "" {
+		pattern := strings.ToLower(strings.Trim(f.path, "/"))
+		_, err := path.Match(pattern, "")
+		if err != nil {
+			return nil, fmt.Errorf("invalid path pattern %q: %v", f.path, err)
+		}
+		filters = append(filters, matchGlob(pattern))
+	}
+
+	if f.modifiedSince != "" {
+		t, err := parseSince(f.modifiedSince, now)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, func(n *rmtool.Node) bool {
+			return n.LastModified().After(t)
+		})
+	}
+
+	return filters, nil
+}
+
+func notInTrash(n *rmtool.Node) bool {
+	return !inTrash(n)
+}
+
+func matchExact(name string) rmtool.NodeFilter {
+	return func(n *rmtool.Node) bool {
+		return n.Name() == name
+	}
+}
+
+func matchIDs(ids []string) rmtool.NodeFilter {
+	return func(n *rmtool.Node) bool {
+		for _, id := range ids {
+			if n.ID() == id {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// matchGlob matches the folder path and name of a notebook against a
+// (lowercase) pattern, e.g. "work/*/minutes*".
+func matchGlob(pattern string) rmtool.NodeFilter {
+	return func(n *rmtool.Node) bool {
+		p := strings.ToLower(strings.Join(append(folderPath(n), n.Name()), "/"))
+		ok, _ := path.Match(pattern, p)
+		return ok
+	}
+}
+
+// parseSince parses a date, a timestamp or a duration before now.
+// Durations can also be given in days, e.g. "7d".
+func parseSince(s string, now time.Time) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", time.RFC3339} {
+		t, err := time.ParseInLocation(layout, s, time.Local)
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err == nil && days >= 0 {
+			return now.AddDate(0, 0, -days), nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("invalid time %q, use a date like \"2006-01-02\" or a duration like \"36h\" or \"7d\"", s)
+	}
+	return now.Add(-d), nil
+}
+
+// readTags reads the tags of a notebook from its content file.
+// Tags are only available for notebooks from newer software versions.
+func readTags(repo rmtool.Repository, n *rmtool.Node) ([]string, error) {
+	r, err := repo.Reader(n.ID(), n.Version(), n.ID()+".content")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tags for %q: %v", n.Name(), err)
+	}
+	defer r.Close()
+
+	var c struct {
+		Tags []struct {
+			Name string `json:"name"`
+		} `json:"tags"`
+	}
+	err = json.NewDecoder(r).Decode(&c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tags for %q: %v", n.Name(), err)
+	}
+
+	tags := make([]string, len(c.Tags))
+	for i, t := range c.Tags {
+		tags[i] = t.Name
+	}
+	return tags, nil
+}
+
+// hasTags tells if all wanted tags are in the list (case insensitive).
+func hasTags(tags, wanted []string) bool {
+	for _, w := range wanted {
+		found := false
+		for _, t := range tags {
+			if strings.EqualFold(t, w) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func isYes(s string) bool {
+	s = strings.ToLower(strings.TrimSpace(s))
+	return s == "y" || s == "yes"
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/akeil/rmtool"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSince(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2021, 2, 10, 12, 30, 0, 0, time.Local)
+
+	cases := []struct {
+		s        string
+		expected time.Time
+	}{
+		{"2021-01-31", time.Date(2021, 1, 31, 0, 0, 0, 0, time.Local)},
+		{"2021-01-31T08:15:00Z", time.Date(2021, 1, 31, 8, 15, 0, 0, time.UTC)},
+		{"36h", now.Add(-36 * time.Hour)},
+		{"90m", now.Add(-90 * time.Minute)},
+		{"7d", now.AddDate(0, 0, -7)},
+		{"0d", now},
+		{"0s", now},
+	}
+	for _, c := range cases {
+		actual, err := parseSince(c.s, now)
+		assert.Nil(err, c.s)
+		assert.True(c.expected.Equal(actual), "%v: %v", c.s, actual)
+	}
+
+	for _, s := range []string{"", "x", "7", "-1d", "-5h", "7days", "d", "1.5d", "2021-13-01", "2021-02-30", "31.01.2021"} {
+		_, err := parseSince(s, now)
+		assert.Error(err, s)
+	}
+}
+
+func TestSelectFilters(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2021, 2, 10, 12, 0, 0, 0, time.Local)
+	root := testTree(
+		&localMeta{id: "work", VisibleName: "Work", Kind: rmtool.CollectionType},
+		&localMeta{id: "minutes", VisibleName: "Minutes", Kind: rmtool.CollectionType, ParentID: "work"},
+		&localMeta{id: "nb1", VisibleName: "Meeting Notes", Kind: rmtool.DocumentType, ParentID: "minutes", lastModified: now.AddDate(0, 0, -2)},
+		&localMeta{id: "nb2", VisibleName: "Notes", Kind: rmtool.DocumentType, ParentID: "work", lastModified: now.AddDate(0, 0, -20)},
+		&localMeta{id: "nb3", VisibleName: "Shopping", Kind: rmtool.DocumentType, lastModified: now.AddDate(0, 0, -1)},
+		&localMeta{id: "nb4", VisibleName: "Old Notes", Kind: rmtool.DocumentType, ParentID: rmtool.TrashFolder, lastModified: now},
+	)
+
+	cases := []struct {
+		name     string
+		flags    selectFlags
+		expected []string
+	}{
+		{"name", selectFlags{name: "notes"}, []string{"nb1", "nb2"}},
+		{"exact name", selectFlags{name: "Notes", exact: true}, []string{"nb2"}},
+		{"exact name is case sensitive", selectFlags{name: "notes", exact: true}, []string{}},
+		{"ids", selectFlags{ids: []string{"nb2", "nb3", "nb4"}}, []string{"nb2", "nb3"}},
+		{"path", selectFlags{path: "Work/*"}, []string{"nb2"}},
+		{"nested path", selectFlags{path: "/work/*/*/"}, []string{"nb1"}},
+		{"modified since date", selectFlags{modifiedSince: "2021-02-05"}, []string{"nb1", "nb3"}},
+		{"modified since duration", selectFlags{modifiedSince: "36h"}, []string{"nb3"}},
+		{"name and path", selectFlags{name: "notes", path: "work/minutes/*"}, []string{"nb1"}},
+		{"name and modified", selectFlags{name: "notes", modifiedSince: "7d"}, []string{"nb1"}},
+		{"ids and name", selectFlags{ids: []string{"nb2", "nb3"}, name: "shop"}, []string{"nb3"}},
+		{"no match", selectFlags{name: "notes", path: "shopping"}, []string{}},
+		// tags are checked separately, all other notebooks match
+		{"tags only", selectFlags{tags: []string{"todo"}}, []string{"nb1", "nb2", "nb3"}},
+	}
+	for _, c := range cases {
+		filters, err := c.flags.filters(now)
+		assert.Nil(err, c.name)
+		assert.Equal(c.expected, documentIDs(root.Filtered(filters...)), c.name)
+	}
+
+	invalid := []struct {
+		name  string
+		flags selectFlags
+	}{
+		{"nothing selected", selectFlags{}},
+		{"only yes", selectFlags{yes: true}},
+		{"exact without name", selectFlags{exact: true, path: "work/*"}},
+		{"invalid path", selectFlags{path: "work/[a"}},
+		{"invalid since", selectFlags{name: "notes", modifiedSince: "yesterday"}},
+		{"negative since", selectFlags{modifiedSince: "-7d"}},
+	}
+	for _, c := range invalid {
+		_, err := c.flags.filters(now)
+		assert.Error(err, c.name)
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	assert := assert.New(t)
+
+	root := testTree(
+		&localMeta{id: "work", VisibleName: "Work", Kind: rmtool.CollectionType},
+		&localMeta{id: "nb1", VisibleName: "Minutes 2021", Kind: rmtool.DocumentType, ParentID: "work"},
+		&localMeta{id: "nb2", VisibleName: "Notes", Kind: rmtool.DocumentType},
+	)
+	nb1 := findNode(root, "nb1")
+	nb2 := findNode(root, "nb2")
+
+	cases := []struct {
+		pattern  string
+		node     *rmtool.Node
+		expected bool
+	}{
+		{"work/minutes 2021", nb1, true},
+		{"work/*", nb1, true},
+		{"*/minutes*", nb1, true},
+		{"work/minutes ????", nb1, true},
+		{"minutes*", nb1, false},
+		{"*", nb1, false},
+		{"*", nb2, true},
+		{"notes", nb2, true},
+		{"work/*", nb2, false},
+	}
+	for _, c := range cases {
+		assert.Equal(c.expected, matchGlob(c.pattern)(c.node), c.pattern+" "+c.node.Name())
+	}
+}
+
+func TestMatchIDs(t *testing.T) {
+	assert := assert.New(t)
+
+	root := testTree(
+		&localMeta{id: "nb1", VisibleName: "One", Kind: rmtool.DocumentType},
+		&localMeta{id: "nb2", VisibleName: "Two", Kind: rmtool.DocumentType},
+	)
+	nb1 := findNode(root, "nb1")
+
+	assert.True(matchIDs([]string{"nb1"})(nb1))
+	assert.True(matchIDs([]string{"nb2", "nb1"})(nb1))
+	assert.False(matchIDs([]string{"nb2"})(nb1))
+	assert.False(matchIDs([]string{"NB1", "nb"})(nb1))
+	assert.False(matchIDs(nil)(nb1))
+}
+
+func TestHasTags(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := []struct {
+		name     string
+		tags     []string
+		wanted   []string
+		expected bool
+	}{
+		{"nothing wanted", []string{"a"}, nil, true},
+		{"no tags", nil, []string{"todo"}, false},
+		{"match", []string{"work", "todo"}, []string{"todo"}, true},
+		{"case insensitive", []string{"ToDo"}, []string{"todo"}, true},
+		{"all wanted", []string{"work", "todo"}, []string{"todo", "work"}, true},
+		{"one missing", []string{"work"}, []string{"todo", "work"}, false},
+		{"no partial match", []string{"todos"}, []string{"todo"}, false},
+	}
+	for _, c := range cases {
+		assert.Equal(c.expected, hasTags(c.tags, c.wanted), c.name)
+	}
+}
+
+func TestReadTags(t *testing.T) {
+	assert := assert.New(t)
+
+	base, err := ioutil.TempDir("", "rescript-test")
+	assert.Nil(err)
+	defer os.RemoveAll(base)
+
+	write := func(name, content string) {
+		assert.Nil(ioutil.WriteFile(filepath.Join(base, name), []byte(content), 0644))
+	}
+	write("tagged.content", `{"pages": [], "tags": [{"name": "Work", "timestamp": 1}, {"name": "todo"}]}`)
+	write("plain.content", `{"pages": []}`)
+	write("broken.content", `{"tags": `)
+
+	repo := newLocalRepo(base)
+	root := testTree(
+		&localMeta{id: "tagged", VisibleName: "Tagged", Kind: rmtool.DocumentType},
+		&localMeta{id: "plain", VisibleName: "Plain", Kind: rmtool.DocumentType},
+		&localMeta{id: "broken", VisibleName: "Broken", Kind: rmtool.DocumentType},
+		&localMeta{id: "missing", VisibleName: "Missing", Kind: rmtool.DocumentType},
+	)
+
+	tags, err := readTags(repo, findNode(root, "tagged"))
+	assert.Nil(err)
+	assert.Equal([]string{"Work", "todo"}, tags)
+
+	tags, err = readTags(repo, findNode(root, "plain"))
+	assert.Nil(err)
+	assert.Equal([]string{}, tags)
+
+	for _, id := range []string{"broken", "missing"} {
+		_, err = readTags(repo, findNode(root, id))
+		assert.Error(err, id)
+	}
+
+	// combined with other selectors
+	sf := selectFlags{name: "e", tags: []string{"work"}}
+	selected, count, err := selectNotebooks(repo, testTree(
+		&localMeta{id: "tagged", VisibleName: "Tagged", Kind: rmtool.DocumentType},
+		&localMeta{id: "plain", VisibleName: "Plain", Kind: rmtool.DocumentType},
+	), sf)
+	assert.Nil(err)
+	assert.Equal(1, count)
+	assert.Equal([]string{"tagged"}, documentIDs(selected))
+
+	// notebooks without a readable content file cannot be filtered by tag
+	_, _, err = selectNotebooks(repo, root, selectFlags{tags: []string{"work"}})
+	assert.Error(err)
+
+	// no tags is not an error, but nothing matches
+	_, _, err = selectNotebooks(repo, root, selectFlags{name: "plain", tags: []string{"work"}})
+	assert.EqualError(err, "no matching notebooks")
+}
+
+func TestIsYes(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, s := range []string{"y", "Y", "yes", "YES", " yes\n", "y\r\n"} {
+		assert.True(isYes(s), s)
+	}
+	for _, s := range []string{"", "n", "no", "yess", "ja", "y y"} {
+		assert.False(isYes(s), s)
+	}
+}
+
+// documentIDs returns the sorted IDs of the notebooks in a tree.
+func documentIDs(root *rmtool.Node) []string {
+	ids := make([]string, 0)
+	root.Walk(func(n *rmtool.Node) error {
+		if rmtool.IsDocument(n) {
+			ids = append(ids, n.ID())
+		}
+		return nil
+	})
+	sort.Strings(ids)
+	return ids
+}
+
+func TestJobTags(t *testing.T) {
+	assert := assert.New(t)
+
+	base, err := ioutil.TempDir("", "rescript-test")
+	assert.Nil(err)
+	defer os.RemoveAll(base)
+	assert.Nil(ioutil.WriteFile(filepath.Join(base, "tagged.content"), []byte(`{"tags": [{"name": "todo"}]}`), 0644))
+	assert.Nil(ioutil.WriteFile(filepath.Join(base, "broken.content"), []byte(`{"tags": `), 0644))
+
+	root := testTree(
+		&localMeta{id: "tagged", VisibleName: "Tagged", Kind: rmtool.DocumentType},
+		&localMeta{id: "broken", VisibleName: "Broken", Kind: rmtool.DocumentType},
+		&localMeta{id: "missing", VisibleName: "Missing", Kind: rmtool.DocumentType},
+	)
+
+	for _, require := range []bool{false, true} {
+		j := &job{repo: newLocalRepo(base), requireTags: require}
+
+		tags, err := j.tags(findNode(root, "tagged"))
+		assert.Nil(err)
+		assert.Equal([]string{"todo"}, tags)
+
+		// without --tag, the notebook is converted without tags
+		for _, id := range []string{"broken", "missing"} {
+			tags, err = j.tags(findNode(root, id))
+			if require {
+				assert.Error(err, id)
+			} else {
+				assert.Nil(err, id)
+				assert.Nil(tags, id)
+			}
+		}
+	}
+}
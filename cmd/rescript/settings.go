@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/akeil/rescript"
+)
+
+type settings struct {
+	DataDir  string
+	CacheDir string
+	AppKey   string
+	HmacKey  string
+	// RenderDir contains the brush images for PDF output.
+	RenderDir string
+	Strokes   []rescript.StrokeRule
+	// MonthlyLimit is the maximum number of MyScript requests per month,
+	// zero for no limit.
+	MonthlyLimit int
+	// Timing overrides the estimation of stroke timestamps.
+	Timing timingSettings
+}
+
+// timingSettings hold the parameters for estimated timestamps,
+// see rescript.Converter. Zero values keep the defaults.
+type timingSettings struct {
+	SpeedFactor int64
+	MinSpeed    float64
+	StrokeGap   int64
+}
+
+func (s settings) tokenPath() string {
+	return filepath.Join(s.DataDir, "device-token")
+}
+
+func (s settings) usagePath() string {
+	return filepath.Join(s.DataDir, "usage.json")
+}
+
+func (s settings) hwrCache() string {
+	return filepath.Join(s.CacheDir, "hwr")
+}
+
+func loadSettings() (settings, error) {
+	s := settings{}
+	path, err := configPath()
+	if err != nil {
+		return s, err
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return s, fmt.Errorf("no configuration file at %q, create one with \"rescript config init\"", path)
+	} else if err != nil {
+		return s, err
+	}
+	defer f.Close()
+
+	err = yaml.NewDecoder(f).Decode(&s)
+	if err != nil {
+		return s, err
+	}
+
+	return s, nil
+}
+
+func loadToken(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	d, err := ioutil.ReadAll(f)
+	if err != nil {
+		return "", err
+	}
+
+	return string(d), err
+}
+
+func saveToken(path, token string) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write([]byte(token))
+	return err
+}
+
+// configPath returns the location of the configuration file.
+func configPath() (string, error) {
+	config, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(config, "rmhwr-conf.yaml"), nil
+}
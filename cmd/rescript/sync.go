@@ -0,0 +1,385 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/akeil/rmtool"
+	"golang.org/x/sync/errgroup"
+)
+
+// manifestName is the name of the manifest file in the output directory.
+const manifestName = ".rescript-manifest.json"
+
+// syncFlags holds the command line options for the sync command.
+type syncFlags struct {
+	convertFlags
+	folder string
+	out    string
+}
+
+func doSync(s settings, f syncFlags) error {
+	r, err := setupRepo(s)
+	if err != nil {
+		return err
+	}
+
+	j, err := newJob(s, f.convertFlags, r)
+	if err != nil {
+		return err
+	}
+
+	stats, err := syncFolder(j, f.folder, f.out)
+	if err != nil {
+		return err
+	}
+
+	success("Done, %v.", stats)
+	return nil
+}
+
+// A manifest records which notebooks were converted to which output files.
+// It is stored in the output directory to make repeated syncs cheap.
+type manifest struct {
+	// Options describes the conversion flags and settings. If they change,
+	// all notebooks are converted again.
+	Options   string                   `json:"options"`
+	Notebooks map[string]manifestEntry `json:"notebooks"`
+}
+
+type manifestEntry struct {
+	Name     string    `json:"name"`
+	Version  uint      `json:"version"`
+	Modified time.Time `json:"modified"`
+	// Path is the output file, relative to the output directory.
+	Path string `json:"path"`
+}
+
+// fingerprint describes the flags and settings that change the output
+// of a job.
+func (j *job) fingerprint() string {
+	s := j.settings
+	return fmt.Sprintf("%+v strokes=%+v timing=%+v render=%q", j.flags, s.Strokes, s.Timing, s.RenderDir)
+}
+
+// unchanged tells if a notebook is the same as when it was converted.
+// The time is compared as well because notebooks in a local directory keep
+// their version until they are synced with the cloud.
+func (e manifestEntry) unchanged(n *rmtool.Node) bool {
+	return e.Version == n.Version() && e.Modified.Equal(n.LastModified())
+}
+
+func loadManifest(dir string) (*manifest, error) {
+	m := &manifest{Notebooks: make(map[string]manifestEntry)}
+
+	f, err := os.Open(filepath.Join(dir, manifestName))
+	if os.IsNotExist(err) {
+		return m, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	err = json.NewDecoder(f).Decode(m)
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest: %v", err)
+	}
+	if m.Notebooks == nil {
+		m.Notebooks = make(map[string]manifestEntry)
+	}
+	return m, nil
+}
+
+func (m *manifest) save(dir string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(dir, manifestName), func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// syncStats counts what happened during a sync.
+type syncStats struct {
+	converted int
+	moved     int
+	deleted   int
+	unchanged int
+}
+
+func (s syncStats) String() string {
+	return fmt.Sprintf("%d converted, %d moved, %d deleted, %d unchanged", s.converted, s.moved, s.deleted, s.unchanged)
+}
+
+// syncFolder converts all notebooks below the given folder and writes the
+// output to a directory tree that mirrors the folders on the tablet.
+//
+// Only notebooks that changed since the last sync are converted. Output files
+// for notebooks that were removed (or moved out of the folder) are deleted.
+func syncFolder(j *job, folder, out string) (syncStats, error) {
+	var stats syncStats
+
+	items, err := j.repo.List()
+	if err != nil {
+		return stats, err
+	}
+	root := rmtool.BuildTree(items)
+
+	base, err := findFolder(root, folder)
+	if err != nil {
+		return stats, err
+	}
+	basePath := folderPath(base)
+	if base != root {
+		basePath = append(basePath, base.Name())
+	}
+
+	man, err := loadManifest(out)
+	if err != nil {
+		return stats, err
+	}
+	prev := man.Notebooks
+	man.Notebooks = make(map[string]manifestEntry)
+
+	// with different options, everything is converted again
+	options := j.fingerprint()
+	changed := man.Options != options
+	man.Options = options
+
+	// collect the notebooks first,
+	// so that all paths are known before any files are changed
+	nodes := make([]*rmtool.Node, 0)
+	base.Walk(func(n *rmtool.Node) error {
+		if rmtool.IsDocument(n) && !inTrash(n) {
+			nodes = append(nodes, n)
+		}
+		return nil
+	})
+
+	// notebooks from the previous sync keep their output path if possible,
+	// new notebooks get the ID added if their name is taken
+	paths := make(map[string]string)
+	used := make(map[string]bool)
+	for _, n := range nodes {
+		if entry, known := prev[n.ID()]; known {
+			paths[n.ID()] = outputPath(n, basePath, j.extension(), entry.Path, used)
+		}
+	}
+	for _, n := range nodes {
+		if _, ok := paths[n.ID()]; !ok {
+			paths[n.ID()] = outputPath(n, basePath, j.extension(), "", used)
+		}
+	}
+
+	moves := make([]fileMove, 0)
+	pending := make([]*rmtool.Node, 0)
+	for _, n := range nodes {
+		rel := paths[n.ID()]
+		entry, known := prev[n.ID()]
+		if changed || !known || !entry.unchanged(n) || !exists(filepath.Join(out, entry.Path)) {
+			pending = append(pending, n)
+			continue
+		}
+
+		if entry.Path != rel {
+			// renamed or moved on the tablet
+			moves = append(moves, fileMove{from: entry.Path, to: rel})
+			entry.Name = n.Name()
+			entry.Path = rel
+			stats.moved++
+		} else {
+			stats.unchanged++
+		}
+		man.Notebooks[n.ID()] = entry
+	}
+
+	for id, e := range prev {
+		if _, ok := paths[id]; ok {
+			continue
+		}
+		success("delete %q", e.Path)
+		// the path may be taken by another notebook now
+		if !used[e.Path] {
+			removeFile(out, e.Path)
+		}
+		stats.deleted++
+	}
+
+	// all moves are done before conversions start,
+	// so that no output is moved away or overwritten by mistake
+	err = moveFiles(out, moves)
+	if err != nil {
+		man.save(out)
+		return stats, err
+	}
+
+	var mx sync.Mutex
+	var group errgroup.Group
+	for _, n := range pending {
+		n := n
+		rel := paths[n.ID()]
+		entry, known := prev[n.ID()]
+		group.Go(func() error {
+			err := j.convert(n, filepath.Join(out, rel))
+
+			mx.Lock()
+			defer mx.Unlock()
+			if err != nil {
+				// keep the output from the previous sync
+				if known {
+					man.Notebooks[n.ID()] = entry
+				}
+				return err
+			}
+
+			if known && entry.Path != rel && !used[entry.Path] {
+				removeFile(out, entry.Path)
+			}
+			man.Notebooks[n.ID()] = manifestEntry{
+				Name:     n.Name(),
+				Version:  n.Version(),
+				Modified: n.LastModified(),
+				Path:     rel,
+			}
+			stats.converted++
+			return nil
+		})
+	}
+	err = group.Wait()
+
+	// save the manifest even if some notebooks failed,
+	// so that the successful conversions are not repeated
+	saveErr := man.save(out)
+	if err != nil {
+		return stats, err
+	}
+	return stats, saveErr
+}
+
+// findFolder looks up a folder by its path, e.g. "Work/Meetings".
+// An empty path refers to the root folder.
+func findFolder(root *rmtool.Node, path string) (*rmtool.Node, error) {
+	if strings.Trim(path, "/") == "" {
+		return root, nil
+	}
+
+	match := rmtool.MatchPath(path)
+	var found *rmtool.Node
+	root.Walk(func(n *rmtool.Node) error {
+		if found == nil && rmtool.IsFolder(n) && match(n) {
+			found = n
+		}
+		return nil
+	})
+	if found == nil {
+		return nil, fmt.Errorf("no folder %q", path)
+	}
+	return found, nil
+}
+
+// outputPath returns the path of the output file for a notebook,
+// relative to the output directory.
+//
+// If the path is already used by another notebook, the ID is added
+// to the file name. If prev is one of these two paths and is not used,
+// it is kept.
+func outputPath(n *rmtool.Node, base []string, ext string, prev string, used map[string]bool) string {
+	parts := make([]string, 0)
+	for _, name := range folderPath(n)[len(base):] {
+		parts = append(parts, sanitizeName(name))
+	}
+
+	p := filepath.Join(append(parts, sanitizeName(n.Name())+"."+ext)...)
+	withID := filepath.Join(append(parts, fmt.Sprintf("%v (%v).%v", sanitizeName(n.Name()), n.ID(), ext))...)
+	if used[p] || (prev == withID && !used[withID]) {
+		p = withID
+	}
+	used[p] = true
+	return p
+}
+
+// inTrash tells if a node is in the trash folder.
+func inTrash(n *rmtool.Node) bool {
+	for p := n.ParentNode; p != nil; p = p.ParentNode {
+		if p.ID() == rmtool.TrashFolder {
+			return true
+		}
+	}
+	return false
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func moveFile(src, dst string) error {
+	err := os.MkdirAll(filepath.Dir(dst), 0755)
+	if err != nil {
+		return err
+	}
+	return os.Rename(src, dst)
+}
+
+// A fileMove moves an output file, paths are relative to the output directory.
+type fileMove struct {
+	from string
+	to   string
+}
+
+// moveFiles moves output files to their new paths.
+//
+// The files are renamed to temporary names first,
+// so that a file can move to the previous path of another one.
+func moveFiles(out string, moves []fileMove) error {
+	tmp := make([]string, len(moves))
+	for i, m := range moves {
+		tmp[i] = filepath.Join(out, m.from) + ".rescript-move"
+		err := os.Rename(filepath.Join(out, m.from), tmp[i])
+		if err != nil {
+			// undo, so that the files are where the manifest says
+			for k := 0; k < i; k++ {
+				os.Rename(tmp[k], filepath.Join(out, moves[k].from))
+			}
+			return err
+		}
+	}
+
+	var err error
+	for i, m := range moves {
+		mvErr := moveFile(tmp[i], filepath.Join(out, m.to))
+		if mvErr != nil {
+			err = mvErr
+			continue
+		}
+		success("move %q to %q", m.from, m.to)
+		pruneDirs(out, filepath.Dir(m.from))
+	}
+	return err
+}
+
+// removeFile deletes an output file and the directories that became empty.
+// Errors are ignored because the file may have been deleted by the user.
+func removeFile(out, rel string) {
+	os.Remove(filepath.Join(out, rel))
+	pruneDirs(out, filepath.Dir(rel))
+}
+
+// pruneDirs removes the given directory and its parents
+// (relative to the output directory) as long as they are empty.
+func pruneDirs(out, dir string) {
+	for ; dir != "."; dir = filepath.Dir(dir) {
+		// fails if the directory is not empty
+		if os.Remove(filepath.Join(out, dir)) != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/akeil/rmtool"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/akeil/rescript"
+)
+
+func TestOutputPath(t *testing.T) {
+	assert := assert.New(t)
+
+	a := rmtool.NewNotebook("Notes", "")
+	b := rmtool.NewNotebook("Notes", "")
+	c := rmtool.NewNotebook("Notes", "")
+	root := rmtool.BuildTree([]rmtool.Meta{a, b, c})
+	node := func(id string) *rmtool.Node {
+		return findNode(root, id)
+	}
+	withID := func(id string) string {
+		return "Notes (" + id + ").md"
+	}
+
+	// known notebooks keep their path, a new one gets the ID
+	used := make(map[string]bool)
+	assert.Equal(withID(b.ID()), outputPath(node(b.ID()), nil, "md", withID(b.ID()), used))
+	assert.Equal("Notes.md", outputPath(node(a.ID()), nil, "md", "Notes.md", used))
+	assert.Equal(withID(c.ID()), outputPath(node(c.ID()), nil, "md", "", used))
+
+	// the path with the ID is only kept while the name is taken
+	used = make(map[string]bool)
+	assert.Equal("Notes.md", outputPath(node(b.ID()), nil, "md", "Other.md", used))
+	assert.Equal(withID(a.ID()), outputPath(node(a.ID()), nil, "md", "", used))
+}
+
+func TestMoveFiles(t *testing.T) {
+	assert := assert.New(t)
+
+	out, err := ioutil.TempDir("", "rescript-test")
+	assert.Nil(err)
+	defer os.RemoveAll(out)
+
+	write := func(rel, content string) {
+		assert.Nil(os.MkdirAll(filepath.Dir(filepath.Join(out, rel)), 0755))
+		assert.Nil(ioutil.WriteFile(filepath.Join(out, rel), []byte(content), 0644))
+	}
+	read := func(rel string) string {
+		data, err := ioutil.ReadFile(filepath.Join(out, rel))
+		assert.Nil(err)
+		return string(data)
+	}
+	write("a.md", "A")
+	write("b.md", "B")
+	write("old/c.md", "C")
+
+	// two files swap their paths
+	err = moveFiles(out, []fileMove{
+		fileMove{from: "a.md", to: "b.md"},
+		fileMove{from: "b.md", to: "a.md"},
+		fileMove{from: "old/c.md", to: "new/c.md"},
+	})
+	assert.Nil(err)
+	assert.Equal("B", read("a.md"))
+	assert.Equal("A", read("b.md"))
+	assert.Equal("C", read("new/c.md"))
+	assert.False(exists(filepath.Join(out, "old")))
+}
+
+func TestSyncSettingsChanged(t *testing.T) {
+	assert := assert.New(t)
+
+	base, err := ioutil.TempDir("", "rescript-test")
+	assert.Nil(err)
+	defer os.RemoveAll(base)
+	src := filepath.Join(base, "src")
+	out := filepath.Join(base, "out")
+	assert.Nil(os.Mkdir(src, 0755))
+
+	// an empty notebook can be converted without requests
+	write := func(name, content string) {
+		assert.Nil(ioutil.WriteFile(filepath.Join(src, name), []byte(content), 0644))
+	}
+	write("nb.metadata", `{"visibleName": "Notes", "type": "DocumentType", "version": 1, "lastModified": "1612085400000"}`)
+	write("nb.content", `{"fileType": "notebook", "pages": []}`)
+
+	j := &job{
+		repo:     newLocalRepo(src),
+		rec:      rescript.NewRecognizer("", "", ""),
+		flags:    convertFlags{format: "txt", lang: "en"},
+		lang:     rescript.LangEN,
+		compose:  rescript.NewPlaintextComposer(),
+		pipeline: rescript.BuildPipeline(),
+	}
+
+	syncWith := func(s settings) syncStats {
+		j.settings = s
+		stats, err := syncFolder(j, "", out)
+		assert.Nil(err)
+		return stats
+	}
+
+	stats := syncWith(settings{})
+	assert.Equal(1, stats.converted)
+	assert.True(exists(filepath.Join(out, "Notes.txt")))
+
+	stats = syncWith(settings{AppKey: "other"})
+	assert.Equal(0, stats.converted, "settings without effect on the output")
+	assert.Equal(1, stats.unchanged)
+
+	changes := []settings{
+		{Timing: timingSettings{StrokeGap: 1000}},
+		{Timing: timingSettings{StrokeGap: 1000}, Strokes: []rescript.StrokeRule{{Colors: []string{"grey"}, Exclude: true}}},
+		{Timing: timingSettings{StrokeGap: 1000}, Strokes: []rescript.StrokeRule{{Colors: []string{"white"}, Exclude: true}}},
+		{RenderDir: "/brushes"},
+	}
+	for _, s := range changes {
+		stats = syncWith(s)
+		assert.Equal(1, stats.converted, "%+v", s)
+		stats = syncWith(s)
+		assert.Equal(0, stats.converted, "%+v", s)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/akeil/rescript"
+)
+
+// doUsage shows the requests to the MyScript API per day
+// for the given month ("2006-01"), or for the current month.
+func doUsage(s settings, month string) error {
+	t := time.Now()
+	if month != "" {
+		var err error
+		t, err = time.ParseInLocation("2006-01", month, time.Local)
+		if err != nil {
+			return fmt.Errorf("invalid month %q, use e.g. \"2021-01\"", month)
+		}
+	}
+
+	u, err := rescript.LoadUsage(s.usagePath())
+	if err != nil {
+		return err
+	}
+
+	for _, day := range u.Days(t) {
+		d, _ := time.ParseInLocation("2006-01-02", day, time.Local)
+		fmt.Printf("%v: %v\n", day, describeUsage(u.Day(d)))
+	}
+
+	total := u.Month(t)
+	fmt.Printf("%v: %v\n", t.Format("January 2006"), describeUsage(total))
+	if s.MonthlyLimit > 0 {
+		left := s.MonthlyLimit - total.Requests
+		if left < 0 {
+			left = 0
+		}
+		fmt.Printf("Limit: %d requests per month, %d left\n", s.MonthlyLimit, left)
+	}
+	return nil
+}
+
+func describeUsage(d rescript.DailyUsage) string {
+	return fmt.Sprintf("%d requests, %d errors, %v sent, %v received",
+		d.Requests, d.Errors, formatSize(d.BytesSent), formatSize(d.BytesReceived))
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/akeil/rmtool"
+)
+
+// watchFlags holds the command line options for the watch command.
+type watchFlags struct {
+	syncFlags
+	interval time.Duration
+	local    string
+}
+
+// doWatch syncs a folder at regular intervals until interrupted.
+//
+// Errors during a sync are reported, but do not stop the watch because they
+// are often temporary, e.g. if the network is down.
+func doWatch(s settings, f watchFlags) error {
+	if f.interval <= 0 {
+		return fmt.Errorf("invalid interval %v, must be greater than zero", f.interval)
+	}
+
+	var r rmtool.Repository
+	var err error
+	if f.local != "" {
+		r = newLocalRepo(f.local)
+	} else {
+		r, err = setupRepo(s)
+		if err != nil {
+			return err
+		}
+	}
+
+	j, err := newJob(s, f.convertFlags, r)
+	if err != nil {
+		return err
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	progress("watch %q every %v, press Ctrl+C to stop", f.folder, f.interval)
+	for {
+		stats, err := syncFolder(j, f.folder, f.out)
+		if err != nil {
+			failure(err)
+		} else if stats.converted+stats.moved+stats.deleted != 0 {
+			success("%v, %v.", time.Now().Format("15:04:05"), stats)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-interrupt:
+			success("Stopped.")
+			return nil
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package rescript
 
 import (
+	"fmt"
 	"math"
+	"sort"
 	"time"
 
 	"github.com/akeil/rmtool/pkg/lines"
@@ -9,33 +11,133 @@ import (
 
 const (
 	speedFactor int64 = 200 * 100
-	strokeGap         = 500 * 100
+	strokeGap   int64 = 500 * 100
 	minSpeed          = 0.01
 )
 
+// DefaultBlockGap is the default distance (in pixels) between two strokes
+// that separates them into different blocks.
+const DefaultBlockGap = 100.0
+
+// A Converter turns the strokes from a reMarkable drawing into MyScript strokes.
+//
+// The MyScript API expects a timestamp for each point. If the drawing carries
+// recorded timing information, it is used. Otherwise, the timestamps are
+// estimated from the pen speed which is recorded for each dot.
+type Converter struct {
+	// SpeedFactor is divided by the pen speed of a dot to estimate the time
+	// in milliseconds since the previous dot.
+	// The default of 20000 gives 200ms for a speed of 100.
+	SpeedFactor int64
+	// MinSpeed is the lower bound for the pen speed, avoids division by zero.
+	MinSpeed float64
+	// StrokeGap is the time in milliseconds that is added between two strokes.
+	// The default of 50000 (50s) is much longer than a real pause
+	// so that MyScript never joins two strokes.
+	StrokeGap int64
+	// Timestamps can supply recorded timestamps for the dots of a stroke,
+	// one for each dot. If it is nil or returns false, the timestamps
+	// are estimated.
+	//
+	// The .rm formats v3 and v5 do not contain timestamps, so this is only
+	// useful with drawings from other sources.
+	Timestamps func(s lines.Stroke) ([]time.Time, bool)
+	// Transform maps device coordinates to the coordinates sent to the API.
+	Transform Transform
+	// Filters select the strokes that are recognized.
+	// A stroke must be accepted by all filters.
+	// Erasers and highlighters are never recognized.
+	Filters []StrokeFilter
+}
+
+// NewConverter creates a converter with default settings.
+func NewConverter() *Converter {
+	return &Converter{
+		SpeedFactor: speedFactor,
+		MinSpeed:    minSpeed,
+		StrokeGap:   strokeGap,
+	}
+}
+
 // ConvertLayer convert a Layer from a reMarkable drawing to a MyScript stroke group.
+//
+// All strokes are placed in one group with the default pen style;
+// use a Converter to group strokes by pen style.
+//
+// Timestamps start after tOffset, the returned value is the timestamp
+// after the last stroke.
 func ConvertLayer(tOffset int64, l lines.Layer) (StrokeGroup, int64) {
+	groups, t := NewConverter().ConvertLayer(tOffset, l)
+	g := StrokeGroup{
+		Strokes:  make([]Stroke, 0),
+		PenStyle: defaultPenStyle,
+	}
+	for _, sg := range groups {
+		g.Strokes = append(g.Strokes, sg.Strokes...)
+	}
+	return g, t
+}
+
+// ConvertLayer convert a Layer from a reMarkable drawing to MyScript stroke groups.
+//
+// Consecutive strokes with the same pen style (brush, color and size) are
+// placed in the same group.
+//
+// Timestamps start after tOffset, the returned value is the timestamp
+// after the last stroke.
+func (c *Converter) ConvertLayer(tOffset int64, l lines.Layer) ([]StrokeGroup, int64) {
 	t := tOffset
-	strokes := make([]Stroke, len(l.Strokes))
+	groups := make([]StrokeGroup, 0)
 
-	i := 0
 	for _, s := range l.Strokes {
-		if isTextStroke(s.BrushType) {
-			stroke, tx := convertStroke(t, s)
-			strokes[i] = stroke
-			// add some millis to t for each new stroke
-			t = tx + strokeGap
-			i++
+		if !c.accepts(s) {
+			continue
+		}
+
+		stroke, tx := c.convertStroke(t, s)
+		// add some millis to t for each new stroke
+		t = tx + c.StrokeGap
+
+		style := penStyle(s)
+		last := len(groups) - 1
+		if last < 0 || groups[last].PenStyle != style {
+			groups = append(groups, StrokeGroup{
+				PenStyle: style,
+				Strokes:  make([]Stroke, 0),
+			})
+			last++
 		}
+		groups[last].Strokes = append(groups[last].Strokes, stroke)
 	}
 
-	return StrokeGroup{
-		Strokes:  strokes[:i],
-		PenStyle: defaultPenStyle,
-	}, t
+	return groups, t
+}
+
+// accepts tells if the given stroke should be recognized.
+func (c *Converter) accepts(s lines.Stroke) bool {
+	if !isTextStroke(s.BrushType) || !c.Transform.includes(s) {
+		return false
+	}
+	for _, accept := range c.Filters {
+		if !accept(s) {
+			return false
+		}
+	}
+	return true
 }
 
-func convertStroke(tOffset int64, s lines.Stroke) (Stroke, int64) {
+// filter returns a layer with only the strokes that should be recognized.
+func (c *Converter) filter(l lines.Layer) lines.Layer {
+	strokes := make([]lines.Stroke, 0, len(l.Strokes))
+	for _, s := range l.Strokes {
+		if c.accepts(s) {
+			strokes = append(strokes, s)
+		}
+	}
+	return lines.Layer{Strokes: strokes}
+}
+
+func (c *Converter) convertStroke(tOffset int64, s lines.Stroke) (Stroke, int64) {
 	size := len(s.Dots)
 	x := make([]int, size)
 	y := make([]int, size)
@@ -43,20 +145,37 @@ func convertStroke(tOffset int64, s lines.Stroke) (Stroke, int64) {
 	p := make([]float64, size)
 	ms := tOffset
 
+	var recorded []time.Time
+	if c.Timestamps != nil {
+		r, ok := c.Timestamps(s)
+		if ok && len(r) == size {
+			recorded = r
+		}
+	}
+
 	x0 := -1
 	y0 := -1
 	i := 0
-	for _, dot := range s.Dots {
-		x1 := int(math.Round(float64(dot.X)))
-		y1 := int(math.Round(float64(dot.Y)))
+	for j, dot := range s.Dots {
+		tx, ty := c.Transform.Apply(float64(dot.X), float64(dot.Y))
+		x1 := int(math.Round(tx))
+		y1 := int(math.Round(ty))
 		// avoid duplicate points
 		if x0 != x1 || y0 != y1 {
-			s := math.Max(minSpeed, float64(dot.Speed))
-			offset := float64(speedFactor) / s
-			ms += int64(math.Round(offset))
+			if recorded != nil {
+				ms = toMillis(recorded[j])
+			} else {
+				ms += c.estimate(dot)
+			}
+			// timestamps must increase from point to point
+			if i > 0 && ms <= ts[i-1] {
+				ms = ts[i-1] + 1
+			} else if i == 0 && ms <= tOffset {
+				ms = tOffset + 1
+			}
 
-			x[i] = int(math.Round(float64(dot.X)))
-			y[i] = int(math.Round(float64(dot.Y)))
+			x[i] = x1
+			y[i] = y1
 			ts[i] = ms
 			p[i] = coercePressure(dot.Pressure)
 
@@ -76,6 +195,14 @@ func convertStroke(tOffset int64, s lines.Stroke) (Stroke, int64) {
 	}, ms
 }
 
+// estimate guesses the time in milliseconds since the previous dot
+// based on the pen speed.
+func (c *Converter) estimate(d lines.Dot) int64 {
+	s := math.Max(c.MinSpeed, float64(d.Speed))
+	offset := float64(c.SpeedFactor) / s
+	return int64(math.Round(offset))
+}
+
 func coercePressure(p float32) float64 {
 	return math.Max(0.0, math.Min(1.0, float64(p)))
 }
@@ -85,10 +212,54 @@ func toMillis(t time.Time) int64 {
 	return nanos / 1000000
 }
 
-func fromMillis(n int64) time.Time {
-	secs := int64(n / 1000)
-	nanos := (int64(n) - (secs * 1000)) * 1000000
-	return time.Unix(secs, nanos)
+// penStyle describes the brush of a stroke in the style format
+// used by MyScript.
+//
+// The pen width depends on the size and the type of the brush,
+// e.g. a marker is wider than a fineliner of the same size.
+func penStyle(s lines.Stroke) string {
+	c, ok := penColors[s.BrushColor]
+	if !ok {
+		c = penColors[lines.Black]
+	}
+	w, ok := penWidths[s.BrushSize]
+	if !ok {
+		w = penWidths[lines.Medium]
+	}
+	f, ok := brushWidths[s.BrushType]
+	if !ok {
+		f = 1.0
+	}
+	// round to avoid styles like 0.30000000000000004
+	w = math.Round(w*f*100) / 100
+	return fmt.Sprintf("color: %v; -myscript-pen-width: %v;", c, w)
+}
+
+var penColors = map[lines.BrushColor]string{
+	lines.Black: "#000000",
+	lines.Gray:  "#969696",
+	lines.White: "#FFFFFF",
+}
+
+// pen widths in millimeters
+var penWidths = map[lines.BrushSize]float64{
+	lines.Small:  0.2,
+	lines.Medium: 0.4,
+	lines.Large:  0.6,
+}
+
+// brushWidths scales the pen width for a brush type,
+// the default is 1.0 (ballpoint, fineliner).
+var brushWidths = map[lines.BrushType]float64{
+	lines.PaintBrush:         2.0,
+	lines.PaintBrushV5:       2.0,
+	lines.Marker:             2.0,
+	lines.MarkerV5:           2.0,
+	lines.CalligraphyV5:      1.5,
+	lines.Pencil:             1.25,
+	lines.PencilV5:           1.25,
+	lines.MechanicalPencil:   0.75,
+	lines.MechanicalPencilV5: 0.75,
 }
 
 func isTextStroke(bt lines.BrushType) bool {
@@ -111,3 +282,143 @@ func lookupPointer(bt lines.BrushType) PointerType {
 		return Pen
 	}
 }
+
+// Segmentation ---------------------------------------------------------------
+
+// SegmentLayer splits the text strokes from the given layer into blocks,
+// e.g. columns of text or notes in the margin.
+//
+// Strokes are in the same block if their bounding boxes are less than gap
+// pixels apart. Each block is returned as a separate layer, the blocks are
+// sorted in reading order. Within a block, the strokes keep their order.
+func SegmentLayer(l lines.Layer, gap float64) []lines.Layer {
+	blocks := make([]block, 0)
+	for i, s := range l.Strokes {
+		if isTextStroke(s.BrushType) && len(s.Dots) > 0 {
+			blocks = append(blocks, block{
+				indices: []int{i},
+				bounds:  strokeBounds(s),
+			})
+		}
+	}
+
+	// merge blocks until no more blocks are close to each other
+	merged := true
+	for merged {
+		merged = false
+		for i := 0; i < len(blocks); i++ {
+			for j := i + 1; j < len(blocks); j++ {
+				if blocks[i].bounds.near(blocks[j].bounds, gap) {
+					blocks[i].merge(blocks[j])
+					blocks = append(blocks[:j], blocks[j+1:]...)
+					merged = true
+					j--
+				}
+			}
+		}
+	}
+
+	readingOrder(blocks)
+
+	layers := make([]lines.Layer, len(blocks))
+	for i, b := range blocks {
+		sort.Ints(b.indices)
+		strokes := make([]lines.Stroke, len(b.indices))
+		for j, idx := range b.indices {
+			strokes[j] = l.Strokes[idx]
+		}
+		layers[i] = lines.Layer{Strokes: strokes}
+	}
+
+	return layers
+}
+
+// readingOrder sorts blocks from top to bottom.
+//
+// Blocks that overlap vertically (e.g. two columns or a note in the margin)
+// form a band and are sorted from left to right.
+func readingOrder(blocks []block) {
+	if len(blocks) == 0 {
+		return
+	}
+
+	sort.SliceStable(blocks, func(i, j int) bool {
+		return blocks[i].bounds.minY < blocks[j].bounds.minY
+	})
+
+	start := 0
+	bottom := blocks[0].bounds.maxY
+	for i := 1; i <= len(blocks); i++ {
+		if i < len(blocks) && blocks[i].bounds.minY <= bottom {
+			bottom = math.Max(bottom, blocks[i].bounds.maxY)
+			continue
+		}
+
+		band := blocks[start:i]
+		sort.SliceStable(band, func(a, b int) bool {
+			return band[a].bounds.minX < band[b].bounds.minX
+		})
+
+		start = i
+		if i < len(blocks) {
+			bottom = blocks[i].bounds.maxY
+		}
+	}
+}
+
+// mergeLayers combines the strokes from all given layers into one layer.
+func mergeLayers(layers []lines.Layer) lines.Layer {
+	strokes := make([]lines.Stroke, 0)
+	for _, l := range layers {
+		strokes = append(strokes, l.Strokes...)
+	}
+	return lines.Layer{Strokes: strokes}
+}
+
+// A block is a group of strokes, referenced by their index in the layer.
+type block struct {
+	indices []int
+	bounds  rect
+}
+
+func (b *block) merge(o block) {
+	b.indices = append(b.indices, o.indices...)
+	b.bounds = b.bounds.union(o.bounds)
+}
+
+type rect struct {
+	minX, minY, maxX, maxY float64
+}
+
+func strokeBounds(s lines.Stroke) rect {
+	r := rect{
+		minX: math.Inf(1),
+		minY: math.Inf(1),
+		maxX: math.Inf(-1),
+		maxY: math.Inf(-1),
+	}
+	for _, d := range s.Dots {
+		r.minX = math.Min(r.minX, float64(d.X))
+		r.minY = math.Min(r.minY, float64(d.Y))
+		r.maxX = math.Max(r.maxX, float64(d.X))
+		r.maxY = math.Max(r.maxY, float64(d.Y))
+	}
+	return r
+}
+
+func (r rect) union(o rect) rect {
+	return rect{
+		minX: math.Min(r.minX, o.minX),
+		minY: math.Min(r.minY, o.minY),
+		maxX: math.Max(r.maxX, o.maxX),
+		maxY: math.Max(r.maxY, o.maxY),
+	}
+}
+
+// near tells if the distance between the two rectangles is less than gap.
+// Overlapping rectangles have a distance of zero.
+func (r rect) near(o rect, gap float64) bool {
+	dx := math.Max(0, math.Max(o.minX-r.maxX, r.minX-o.maxX))
+	dy := math.Max(0, math.Max(o.minY-r.maxY, r.minY-o.maxY))
+	return dx < gap && dy < gap
+}
@@ -0,0 +1,227 @@
+package rescript
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/akeil/rmtool/pkg/lines"
+)
+
+func TestSegmentLayer(t *testing.T) {
+	assert := assert.New(t)
+
+	l := lines.Layer{
+		Strokes: []lines.Stroke{
+			// right column
+			sampleStroke(lines.Fineliner, 800, 300, 1300, 350),
+			sampleStroke(lines.Fineliner, 800, 380, 1300, 430),
+			// heading
+			sampleStroke(lines.Fineliner, 100, 100, 1300, 180),
+			// left column
+			sampleStroke(lines.Fineliner, 100, 300, 600, 350),
+			sampleStroke(lines.Fineliner, 100, 380, 600, 430),
+			// erased, ignored
+			sampleStroke(lines.Eraser, 100, 1000, 600, 1050),
+		},
+	}
+
+	blocks := SegmentLayer(l, DefaultBlockGap)
+	assert.Equal(3, len(blocks))
+
+	// reading order: heading, left column, right column
+	assert.Equal(1, len(blocks[0].Strokes))
+	assert.Equal(float32(100), blocks[0].Strokes[0].Dots[0].Y)
+
+	assert.Equal(2, len(blocks[1].Strokes))
+	assert.Equal(float32(100), blocks[1].Strokes[0].Dots[0].X)
+	assert.Equal(float32(300), blocks[1].Strokes[0].Dots[0].Y)
+	assert.Equal(float32(380), blocks[1].Strokes[1].Dots[0].Y)
+
+	assert.Equal(2, len(blocks[2].Strokes))
+	assert.Equal(float32(800), blocks[2].Strokes[0].Dots[0].X)
+
+	// with a large gap, everything is one block
+	blocks = SegmentLayer(l, 1000)
+	assert.Equal(1, len(blocks))
+	assert.Equal(5, len(blocks[0].Strokes))
+
+	// empty layer
+	blocks = SegmentLayer(lines.Layer{}, DefaultBlockGap)
+	assert.Equal(0, len(blocks))
+}
+
+func sampleStroke(bt lines.BrushType, x0, y0, x1, y1 float32) lines.Stroke {
+	return lines.Stroke{
+		BrushType: bt,
+		Dots: []lines.Dot{
+			lines.Dot{X: x0, Y: y0, Speed: 1, Pressure: 0.5},
+			lines.Dot{X: x1, Y: y1, Speed: 1, Pressure: 0.5},
+		},
+	}
+}
+
+func TestConvertLayerOrder(t *testing.T) {
+	assert := assert.New(t)
+
+	l := lines.Layer{
+		Strokes: []lines.Stroke{
+			sampleStroke(lines.Fineliner, 100, 100, 200, 100),
+			sampleStroke(lines.Eraser, 100, 100, 200, 100),
+			sampleStroke(lines.Ballpoint, 300, 100, 400, 100),
+			sampleStroke(lines.Fineliner, 500, 100, 600, 100),
+		},
+	}
+
+	group, tx := ConvertLayer(0, l)
+	g := group.Strokes
+	assert.Equal(defaultPenStyle, group.PenStyle)
+
+	// the eraser is dropped, all other strokes keep their order
+	assert.Equal(3, len(g))
+	assert.Equal(100, g[0].X[0])
+	assert.Equal(300, g[1].X[0])
+	assert.Equal(500, g[2].X[0])
+
+	assertMonotonic(t, g, 0)
+	last := g[2].Timestamp
+	assert.True(tx > last[len(last)-1])
+
+	// a subsequent layer continues after the previous one
+	group, _ = ConvertLayer(tx, l)
+	assertMonotonic(t, group.Strokes, tx)
+}
+
+func TestConvertStrokeDuplicates(t *testing.T) {
+	assert := assert.New(t)
+
+	s := lines.Stroke{
+		BrushType: lines.Fineliner,
+		Dots: []lines.Dot{
+			lines.Dot{X: 10, Y: 10, Speed: 1, Pressure: 0.5},
+			lines.Dot{X: 10.2, Y: 9.8, Speed: 1, Pressure: 0.5},
+			lines.Dot{X: 11, Y: 10, Speed: 1, Pressure: 1.5},
+		},
+	}
+
+	st, _ := NewConverter().convertStroke(0, s)
+	assert.Equal([]int{10, 11}, st.X)
+	assert.Equal([]int{10, 10}, st.Y)
+	assert.Equal(2, len(st.Timestamp))
+	assert.Equal(1.0, st.Pressure[1])
+}
+
+func TestConvertHeuristic(t *testing.T) {
+	assert := assert.New(t)
+
+	c := NewConverter()
+	c.SpeedFactor = 100
+	c.StrokeGap = 1000
+
+	s := lines.Stroke{
+		BrushType: lines.Fineliner,
+		Dots: []lines.Dot{
+			lines.Dot{X: 10, Y: 10, Speed: 1},
+			lines.Dot{X: 20, Y: 10, Speed: 2},
+			// zero speed uses MinSpeed
+			lines.Dot{X: 30, Y: 10, Speed: 0},
+			// very high speed must still increase the timestamp
+			lines.Dot{X: 40, Y: 10, Speed: 1000000},
+		},
+	}
+
+	gs, tx := c.ConvertLayer(0, lines.Layer{Strokes: []lines.Stroke{s}})
+	g := allStrokes(gs)
+	ts := g[0].Timestamp
+	assert.Equal(int64(100), ts[0])
+	assert.Equal(int64(150), ts[1])
+	assert.Equal(int64(150+10000), ts[2])
+	assert.Equal(int64(150+10000+1), ts[3])
+	assert.Equal(ts[3]+1000, tx)
+}
+
+func TestConvertRecordedTimestamps(t *testing.T) {
+	assert := assert.New(t)
+
+	base := time.Date(2021, 1, 10, 12, 0, 0, 0, time.UTC)
+	c := NewConverter()
+	c.Timestamps = func(s lines.Stroke) ([]time.Time, bool) {
+		// only the strokes with a ballpoint have recorded timestamps
+		if s.BrushType != lines.Ballpoint {
+			return nil, false
+		}
+		ts := make([]time.Time, len(s.Dots))
+		for i := range s.Dots {
+			ts[i] = base.Add(time.Duration(s.Dots[i].X) * time.Millisecond)
+		}
+		return ts, true
+	}
+
+	l := lines.Layer{
+		Strokes: []lines.Stroke{
+			sampleStroke(lines.Ballpoint, 100, 100, 200, 100),
+			sampleStroke(lines.Fineliner, 300, 100, 400, 100),
+			// recorded timestamps which are out of order
+			sampleStroke(lines.Ballpoint, 10, 100, 20, 100),
+		},
+	}
+
+	gs, _ := c.ConvertLayer(0, l)
+	g := allStrokes(gs)
+	ms := toMillis(base)
+	assert.Equal([]int64{ms + 100, ms + 200}, g[0].Timestamp)
+	assertMonotonic(t, g, 0)
+}
+
+// assertMonotonic checks that timestamps increase within and across strokes.
+func assertMonotonic(t *testing.T, strokes []Stroke, tOffset int64) {
+	prev := tOffset
+	for i, s := range strokes {
+		assert.Equal(t, len(s.X), len(s.Timestamp))
+		for j, ts := range s.Timestamp {
+			assert.Truef(t, ts > prev, "timestamp %d of stroke %d does not increase", j, i)
+			prev = ts
+		}
+	}
+}
+
+func allStrokes(groups []StrokeGroup) []Stroke {
+	strokes := make([]Stroke, 0)
+	for _, g := range groups {
+		strokes = append(strokes, g.Strokes...)
+	}
+	return strokes
+}
+
+func TestPenStyle(t *testing.T) {
+	assert := assert.New(t)
+
+	style := func(bt lines.BrushType, c lines.BrushColor, size lines.BrushSize) string {
+		return penStyle(lines.Stroke{BrushType: bt, BrushColor: c, BrushSize: size})
+	}
+
+	assert.Equal("color: #000000; -myscript-pen-width: 0.4;", style(lines.Fineliner, lines.Black, lines.Medium))
+	assert.Equal("color: #969696; -myscript-pen-width: 0.2;", style(lines.BallpointV5, lines.Gray, lines.Small))
+	assert.Equal("color: #000000; -myscript-pen-width: 0.8;", style(lines.Marker, lines.Black, lines.Medium))
+	assert.Equal("color: #000000; -myscript-pen-width: 1.2;", style(lines.PaintBrushV5, lines.Black, lines.Large))
+	assert.Equal("color: #000000; -myscript-pen-width: 0.3;", style(lines.MechanicalPencil, lines.Black, lines.Medium))
+	// unknown values fall back to a black, medium pen
+	assert.Equal("color: #000000; -myscript-pen-width: 0.4;", style(lines.BrushType(99), lines.BrushColor(9), lines.BrushSize(9)))
+
+	// the brush type changes the style, even at the same size
+	assert.NotEqual(style(lines.Fineliner, lines.Black, lines.Medium), style(lines.MarkerV5, lines.Black, lines.Medium))
+
+	// strokes with different brushes are in different groups
+	l := lines.Layer{
+		Strokes: []lines.Stroke{
+			sampleStroke(lines.Fineliner, 100, 100, 200, 100),
+			sampleStroke(lines.Ballpoint, 300, 100, 400, 100),
+			sampleStroke(lines.Marker, 500, 100, 600, 100),
+		},
+	}
+	gs, _ := NewConverter().ConvertLayer(0, l)
+	assert.Equal(2, len(gs))
+	assert.Equal(2, len(gs[0].Strokes))
+	assert.Equal(1, len(gs[1].Strokes))
+}
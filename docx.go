@@ -0,0 +1,208 @@
+package rescript
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const wordNamespace = "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+
+// NewDOCXComposer creates a composer which generates a Word document
+// in Office Open XML format (.docx).
+//
+// The document starts with the notebook title; each page starts on a new
+// page with a heading. Use InferStructure to turn headings and lists from the
+// handwriting into Word headings and lists.
+func NewDOCXComposer(opts ...ComposeOption) ComposeFunc {
+	o := newComposeOptions(opts)
+	return func(w io.Writer, m Metadata, r map[string]*Node) error {
+		return composeDOCX(w, m, r, o)
+	}
+}
+
+func composeDOCX(w io.Writer, m Metadata, r map[string]*Node, o composeOptions) error {
+	d := &docxDocument{}
+	body := d.body(m, officePages(m, r, o))
+
+	files := []archiveFile{
+		{"[Content_Types].xml", docxContentTypes},
+		{"_rels/.rels", docxRels},
+		{"docProps/core.xml", docxCoreProperties(m)},
+		{"word/_rels/document.xml.rels", docxDocumentRels},
+		{"word/document.xml", body},
+		{"word/styles.xml", docxStyles},
+		{"word/numbering.xml", d.numbering()},
+	}
+
+	return writeArchive(w, "", files)
+}
+
+// docxDocument keeps track of the numbered lists in a document.
+//
+// Each numbered list needs its own numbering instance so that it does not
+// continue the numbers from the previous list.
+type docxDocument struct {
+	// starts holds the first number for each numbered list.
+	starts []int
+}
+
+const (
+	bulletNumID = 1
+	// numbered lists use IDs after the bullet list
+	firstListNumID = 2
+)
+
+func (d *docxDocument) body(m Metadata, pages []officePage) string {
+	var b strings.Builder
+	b.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\" standalone=\"yes\"?>\n")
+	b.WriteString(fmt.Sprintf("<w:document xmlns:w=\"%v\">\n<w:body>\n", wordNamespace))
+	b.WriteString(docxParagraph("Title", false, 0, []string{m.Title}))
+
+	for i, p := range pages {
+		// each page but the first starts on a new page
+		b.WriteString(docxParagraph("Heading1", i != 0, 0, []string{fmt.Sprintf("Page %d", p.number)}))
+
+		for _, s := range p.sections {
+			depth := 1
+			if s.name != "" {
+				depth = 2
+				b.WriteString(docxParagraph("Heading2", false, 0, []string{s.name}))
+			}
+			d.blocks(&b, s.blocks, depth)
+		}
+	}
+
+	b.WriteString("</w:body>\n</w:document>\n")
+	return b.String()
+}
+
+func (d *docxDocument) blocks(b *strings.Builder, blocks []textBlock, depth int) {
+	numID := 0
+	for i, blk := range blocks {
+		switch blk.kind {
+		case heading:
+			style := fmt.Sprintf("Heading%d", headingLevel(depth, blk.level))
+			b.WriteString(docxParagraph(style, false, 0, blk.lines))
+		case bulletItem:
+			b.WriteString(docxParagraph("ListParagraph", false, bulletNumID, blk.lines))
+		case taskItem:
+			b.WriteString(docxParagraph("ListParagraph", false, bulletNumID, []string{taskMark(blk.checked) + blk.text()}))
+		case numberedItem:
+			if i == 0 || !blk.continues(blocks[i-1]) {
+				d.starts = append(d.starts, blk.number)
+				numID = firstListNumID + len(d.starts) - 1
+			}
+			b.WriteString(docxParagraph("ListParagraph", false, numID, blk.lines))
+		default:
+			b.WriteString(docxParagraph("", false, 0, blk.lines))
+		}
+	}
+}
+
+// docxParagraph creates a paragraph with the given style.
+// Lines are separated by line breaks.
+// If numID is non-zero, the paragraph is an item of that list.
+func docxParagraph(style string, pageBreak bool, numID int, lines []string) string {
+	var b strings.Builder
+	b.WriteString("<w:p>")
+	if style != "" || pageBreak || numID != 0 {
+		b.WriteString("<w:pPr>")
+		if style != "" {
+			b.WriteString(fmt.Sprintf("<w:pStyle w:val=\"%v\"/>", style))
+		}
+		if pageBreak {
+			b.WriteString("<w:pageBreakBefore/>")
+		}
+		if numID != 0 {
+			b.WriteString(fmt.Sprintf("<w:numPr><w:ilvl w:val=\"0\"/><w:numId w:val=\"%d\"/></w:numPr>", numID))
+		}
+		b.WriteString("</w:pPr>")
+	}
+	b.WriteString("<w:r>")
+	for i, line := range lines {
+		if i != 0 {
+			b.WriteString("<w:br/>")
+		}
+		b.WriteString(fmt.Sprintf("<w:t xml:space=\"preserve\">%v</w:t>", escapeXML(line)))
+	}
+	b.WriteString("</w:r></w:p>\n")
+	return b.String()
+}
+
+// numbering creates the list definitions, one for bullets and one for each
+// numbered list.
+func (d *docxDocument) numbering() string {
+	var b strings.Builder
+	b.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\" standalone=\"yes\"?>\n")
+	b.WriteString(fmt.Sprintf("<w:numbering xmlns:w=\"%v\">\n", wordNamespace))
+	b.WriteString(docxAbstractNum(0, "bullet", "•"))
+	b.WriteString(docxAbstractNum(1, "decimal", "%1."))
+	b.WriteString(fmt.Sprintf("<w:num w:numId=\"%d\"><w:abstractNumId w:val=\"0\"/></w:num>\n", bulletNumID))
+	for i, start := range d.starts {
+		b.WriteString(fmt.Sprintf("<w:num w:numId=\"%d\"><w:abstractNumId w:val=\"1\"/>", firstListNumID+i))
+		b.WriteString(fmt.Sprintf("<w:lvlOverride w:ilvl=\"0\"><w:startOverride w:val=\"%d\"/></w:lvlOverride></w:num>\n", start))
+	}
+	b.WriteString("</w:numbering>\n")
+	return b.String()
+}
+
+func docxAbstractNum(id int, format, text string) string {
+	return fmt.Sprintf("<w:abstractNum w:abstractNumId=\"%d\"><w:multiLevelType w:val=\"singleLevel\"/>"+
+		"<w:lvl w:ilvl=\"0\"><w:start w:val=\"1\"/><w:numFmt w:val=\"%v\"/><w:lvlText w:val=\"%v\"/><w:lvlJc w:val=\"left\"/>"+
+		"<w:pPr><w:ind w:left=\"720\" w:hanging=\"360\"/></w:pPr></w:lvl></w:abstractNum>\n", id, format, text)
+}
+
+func docxCoreProperties(m Metadata) string {
+	var b strings.Builder
+	b.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\" standalone=\"yes\"?>\n")
+	b.WriteString("<cp:coreProperties xmlns:cp=\"http://schemas.openxmlformats.org/package/2006/metadata/core-properties\"" +
+		" xmlns:dc=\"http://purl.org/dc/elements/1.1/\" xmlns:dcterms=\"http://purl.org/dc/terms/\"" +
+		" xmlns:xsi=\"http://www.w3.org/2001/XMLSchema-instance\">\n")
+	b.WriteString(fmt.Sprintf("<dc:title>%v</dc:title>\n", escapeXML(m.Title)))
+	if m.Language != "" {
+		b.WriteString(fmt.Sprintf("<dc:language>%v</dc:language>\n", strings.ReplaceAll(string(m.Language), "_", "-")))
+	}
+	if !m.Modified.IsZero() {
+		b.WriteString(fmt.Sprintf("<dcterms:modified xsi:type=\"dcterms:W3CDTF\">%v</dcterms:modified>\n", m.Modified.UTC().Format("2006-01-02T15:04:05Z")))
+	}
+	b.WriteString("</cp:coreProperties>\n")
+	return b.String()
+}
+
+const docxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>
+<Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/>
+<Override PartName="/word/numbering.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.numbering+xml"/>
+<Override PartName="/docProps/core.xml" ContentType="application/vnd.openxmlformats-package.core-properties+xml"/>
+</Types>
+`
+
+const docxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties" Target="docProps/core.xml"/>
+</Relationships>
+`
+
+const docxDocumentRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/numbering" Target="numbering.xml"/>
+</Relationships>
+`
+
+const docxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/><w:pPr><w:spacing w:after="160"/></w:pPr><w:rPr><w:sz w:val="22"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Title"><w:name w:val="Title"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:rPr><w:sz w:val="56"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:keepNext/><w:spacing w:before="240"/><w:outlineLvl w:val="0"/></w:pPr><w:rPr><w:b/><w:sz w:val="32"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Heading2"><w:name w:val="heading 2"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:keepNext/><w:spacing w:before="240"/><w:outlineLvl w:val="1"/></w:pPr><w:rPr><w:b/><w:sz w:val="28"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Heading3"><w:name w:val="heading 3"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:keepNext/><w:spacing w:before="200"/><w:outlineLvl w:val="2"/></w:pPr><w:rPr><w:b/><w:sz w:val="24"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Heading4"><w:name w:val="heading 4"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:keepNext/><w:spacing w:before="200"/><w:outlineLvl w:val="3"/></w:pPr><w:rPr><w:b/><w:i/><w:sz w:val="22"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="ListParagraph"><w:name w:val="List Paragraph"/><w:basedOn w:val="Normal"/><w:pPr><w:spacing w:after="0"/><w:ind w:left="720"/></w:pPr></w:style>
+</w:styles>
+`
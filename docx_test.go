@@ -0,0 +1,62 @@
+package rescript
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// officeSample returns recognition results with two pages and a list.
+func officeSample() (Metadata, map[string]*Node) {
+	m := Metadata{
+		Title:    "Notes & Ideas",
+		PageIDs:  []string{"page0", "page1", "page2"},
+		Language: LangEN,
+	}
+	nodes := map[string]*Node{
+		"page0": buildSampleList("Agenda", "\n", "===", "\n", "-", " ", "one", "\n", "[x]", " ", "two", "\n", "\n", "some", "\n", "<text>"),
+		"page2": buildSampleList("3.", " ", "three", "\n", "4.", " ", "four"),
+	}
+	return m, nodes
+}
+
+func TestComposeDOCX(t *testing.T) {
+	assert := assert.New(t)
+
+	m, nodes := officeSample()
+
+	var buf bytes.Buffer
+	c := NewDOCXComposer(InferStructure())
+	err := c(&buf, m, nodes)
+	assert.Nil(err)
+
+	files := readArchive(t, buf.Bytes())
+	assertWellFormed(t, files)
+	assert.NotContains(files, "mimetype")
+	assert.Contains(files, "[Content_Types].xml")
+	assert.Contains(files, "word/styles.xml")
+	assert.Contains(files["docProps/core.xml"], "<dc:title>Notes &amp; Ideas</dc:title>")
+
+	doc := files["word/document.xml"]
+	assert.Contains(doc, `<w:p><w:pPr><w:pStyle w:val="Title"/></w:pPr><w:r><w:t xml:space="preserve">Notes &amp; Ideas</w:t></w:r></w:p>`)
+	assert.Contains(doc, `<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t xml:space="preserve">Page 1</w:t></w:r></w:p>`)
+	assert.Contains(doc, `<w:p><w:pPr><w:pStyle w:val="Heading2"/></w:pPr><w:r><w:t xml:space="preserve">Agenda</w:t></w:r></w:p>`)
+	assert.Contains(doc, `<w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t xml:space="preserve">one</w:t>`)
+	assert.Contains(doc, `<w:t xml:space="preserve">☒ two</w:t>`)
+	assert.Contains(doc, `<w:p><w:r><w:t xml:space="preserve">some</w:t><w:br/><w:t xml:space="preserve">&lt;text&gt;</w:t></w:r></w:p>`)
+	// the second page starts on a new page
+	assert.Contains(doc, `<w:pStyle w:val="Heading1"/><w:pageBreakBefore/></w:pPr><w:r><w:t xml:space="preserve">Page 3</w:t>`)
+	assert.Contains(doc, `<w:numId w:val="2"/></w:numPr></w:pPr><w:r><w:t xml:space="preserve">four</w:t>`)
+
+	// numbered lists keep their start number
+	assert.Contains(files["word/numbering.xml"], `<w:num w:numId="2"><w:abstractNumId w:val="1"/><w:lvlOverride w:ilvl="0"><w:startOverride w:val="3"/>`)
+}
+
+func TestDOCXError(t *testing.T) {
+	assert := assert.New(t)
+
+	m, nodes := officeSample()
+	err := NewDOCXComposer()(failWriter{}, m, nodes)
+	assert.Error(err)
+}
@@ -0,0 +1,262 @@
+package rescript
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NewEPUBComposer creates a composer which generates an EPUB e-book
+// with the notebook as a single chapter.
+//
+// Use a Book to package several notebooks into one EPUB.
+func NewEPUBComposer(opts ...ComposeOption) ComposeFunc {
+	return func(w io.Writer, m Metadata, r map[string]*Node) error {
+		b := NewBook(m.Title, opts...)
+		b.Add(m, r)
+		return b.Write(w)
+	}
+}
+
+// A Book packages several recognized notebooks into an EPUB e-book
+// with one chapter per notebook.
+//
+// The table of contents follows the folder hierarchy given by the Path of the
+// notebooks. Chapters are sorted by folder and title.
+type Book struct {
+	Title    string
+	opts     composeOptions
+	chapters []chapter
+	mx       sync.Mutex
+}
+
+type chapter struct {
+	m Metadata
+	r map[string]*Node
+}
+
+// NewBook creates an empty book with the given title.
+func NewBook(title string, opts ...ComposeOption) *Book {
+	return &Book{
+		Title:    title,
+		opts:     newComposeOptions(opts),
+		chapters: make([]chapter, 0),
+	}
+}
+
+// Add adds a notebook with its recognition results as a chapter.
+// It is safe to add chapters from multiple goroutines.
+func (b *Book) Add(m Metadata, r map[string]*Node) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	b.chapters = append(b.chapters, chapter{m: m, r: r})
+}
+
+// Len returns the number of chapters.
+func (b *Book) Len() int {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	return len(b.chapters)
+}
+
+// Write writes the book as an EPUB file to the given writer.
+func (b *Book) Write(w io.Writer) error {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	sort.SliceStable(b.chapters, func(i, j int) bool {
+		return DocumentLess(b.chapters[i].m, b.chapters[j].m)
+	})
+
+	files := []archiveFile{
+		{"META-INF/container.xml", epubContainer},
+		{"OEBPS/content.opf", b.packageDocument()},
+		{"OEBPS/nav.xhtml", b.navDocument()},
+	}
+	for i, c := range b.chapters {
+		files = append(files, archiveFile{"OEBPS/" + chapterFile(i), epubChapter(c, b.opts)})
+	}
+
+	return writeArchive(w, "application/epub+zip", files)
+}
+
+const epubContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+<rootfiles>
+<rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+</rootfiles>
+</container>
+`
+
+// packageDocument creates the OPF file with the metadata and the list of files.
+func (b *Book) packageDocument() string {
+	var s strings.Builder
+	s.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	s.WriteString("<package xmlns=\"http://www.idpf.org/2007/opf\" version=\"3.0\" unique-identifier=\"book-id\">\n")
+	s.WriteString("<metadata xmlns:dc=\"http://purl.org/dc/elements/1.1/\">\n")
+	s.WriteString(fmt.Sprintf("<dc:identifier id=\"book-id\">%v</dc:identifier>\n", b.identifier()))
+	s.WriteString(fmt.Sprintf("<dc:title>%v</dc:title>\n", html.EscapeString(b.Title)))
+	s.WriteString(fmt.Sprintf("<dc:language>%v</dc:language>\n", b.language()))
+	s.WriteString(fmt.Sprintf("<meta property=\"dcterms:modified\">%v</meta>\n", b.modified().UTC().Format("2006-01-02T15:04:05Z")))
+	s.WriteString(fmt.Sprintf("<meta name=\"generator\" content=\"rescript %v\"/>\n", Version))
+	s.WriteString("</metadata>\n")
+
+	s.WriteString("<manifest>\n")
+	s.WriteString("<item id=\"nav\" href=\"nav.xhtml\" media-type=\"application/xhtml+xml\" properties=\"nav\"/>\n")
+	for i := range b.chapters {
+		s.WriteString(fmt.Sprintf("<item id=\"chapter-%d\" href=\"%v\" media-type=\"application/xhtml+xml\"/>\n", i+1, chapterFile(i)))
+	}
+	s.WriteString("</manifest>\n")
+
+	s.WriteString("<spine>\n")
+	for i := range b.chapters {
+		s.WriteString(fmt.Sprintf("<itemref idref=\"chapter-%d\"/>\n", i+1))
+	}
+	s.WriteString("</spine>\n")
+	s.WriteString("</package>\n")
+
+	return s.String()
+}
+
+// identifier creates a unique identifier from the IDs of all notebooks.
+func (b *Book) identifier() string {
+	h := sha1.New()
+	for _, c := range b.chapters {
+		io.WriteString(h, c.m.ID)
+	}
+	return fmt.Sprintf("urn:rescript:%x", h.Sum(nil))
+}
+
+// language returns the language of the first chapter as a BCP 47 tag.
+func (b *Book) language() string {
+	for _, c := range b.chapters {
+		if c.m.Language != "" {
+			return strings.ReplaceAll(string(c.m.Language), "_", "-")
+		}
+	}
+	return "en"
+}
+
+// modified returns the time the most recent notebook was modified.
+// If that is unknown, the current time is used.
+func (b *Book) modified() time.Time {
+	var t time.Time
+	for _, c := range b.chapters {
+		if c.m.Modified.After(t) {
+			t = c.m.Modified
+		}
+	}
+	if t.IsZero() {
+		return time.Now()
+	}
+	return t
+}
+
+// navNode is an entry in the table of contents, either a folder or a chapter.
+type navNode struct {
+	name     string
+	chapter  int
+	children []*navNode
+}
+
+// navDocument creates the navigation document with the table of contents.
+// Folders are listed as headings with the chapters they contain.
+func (b *Book) navDocument() string {
+	root := &navNode{chapter: -1}
+	for i, c := range b.chapters {
+		parent := root
+		for _, name := range c.m.Path {
+			parent = parent.folder(name)
+		}
+		parent.children = append(parent.children, &navNode{name: c.m.Title, chapter: i})
+	}
+
+	var s strings.Builder
+	s.WriteString(xhtmlHeader("Contents"))
+	s.WriteString("<nav epub:type=\"toc\" id=\"toc\">\n")
+	s.WriteString("<h1>Contents</h1>\n")
+	root.write(&s)
+	s.WriteString("</nav>\n")
+	s.WriteString("</body>\n</html>\n")
+	return s.String()
+}
+
+// folder returns the child folder with the given name, creating it if needed.
+func (n *navNode) folder(name string) *navNode {
+	for _, c := range n.children {
+		if c.chapter == -1 && c.name == name {
+			return c
+		}
+	}
+	f := &navNode{name: name, chapter: -1}
+	n.children = append(n.children, f)
+	return f
+}
+
+func (n *navNode) write(s *strings.Builder) {
+	s.WriteString("<ol>\n")
+	for _, c := range n.children {
+		if c.chapter == -1 {
+			s.WriteString(fmt.Sprintf("<li><span>%v</span>\n", html.EscapeString(c.name)))
+			c.write(s)
+			s.WriteString("</li>\n")
+		} else {
+			s.WriteString(fmt.Sprintf("<li><a href=\"%v\">%v</a></li>\n", chapterFile(c.chapter), html.EscapeString(c.name)))
+		}
+	}
+	s.WriteString("</ol>\n")
+}
+
+// epubChapter creates the XHTML document for a notebook.
+func epubChapter(c chapter, o composeOptions) string {
+	var s strings.Builder
+	s.WriteString(xhtmlHeader(c.m.Title))
+	s.WriteString(fmt.Sprintf("<h1>%v</h1>\n", html.EscapeString(c.m.Title)))
+
+	for i, pageID := range c.m.PageIDs {
+		tail, ok := c.r[pageID]
+		if !ok {
+			continue
+		}
+
+		s.WriteString(fmt.Sprintf("<section id=\"%v\">\n", pageAnchor(c.m.PageNumber(i))))
+		s.WriteString(fmt.Sprintf("<h2>Page %d</h2>\n", c.m.PageNumber(i)))
+		for _, sec := range o.sections(c.m, pageID, tail) {
+			if sec.name != "" {
+				s.WriteString(fmt.Sprintf("<h3>%v</h3>\n", html.EscapeString(sec.name)))
+			}
+			s.WriteString("<p>")
+			for _, t := range trimNewlines(sec.tokens) {
+				if t.IsNewline() {
+					s.WriteString("<br/>\n")
+				} else {
+					s.WriteString(html.EscapeString(t.String()))
+				}
+			}
+			s.WriteString("</p>\n")
+		}
+		s.WriteString("</section>\n")
+	}
+
+	s.WriteString("</body>\n</html>\n")
+	return s.String()
+}
+
+func xhtmlHeader(title string) string {
+	var s strings.Builder
+	s.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	s.WriteString("<!DOCTYPE html>\n")
+	s.WriteString("<html xmlns=\"http://www.w3.org/1999/xhtml\" xmlns:epub=\"http://www.idpf.org/2007/ops\">\n")
+	s.WriteString(fmt.Sprintf("<head>\n<meta charset=\"utf-8\"/>\n<title>%v</title>\n</head>\n", html.EscapeString(title)))
+	s.WriteString("<body>\n")
+	return s.String()
+}
+
+func chapterFile(idx int) string {
+	return fmt.Sprintf("chapter-%d.xhtml", idx+1)
+}
@@ -0,0 +1,144 @@
+package rescript
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBook(t *testing.T) {
+	assert := assert.New(t)
+
+	b := NewBook("Archive")
+	b.Add(Metadata{
+		ID:       "nb2",
+		Title:    "Standup",
+		PageIDs:  []string{"page0"},
+		Path:     []string{"Work", "Meetings"},
+		Language: LangDE,
+		Modified: time.Date(2020, 10, 1, 9, 30, 0, 0, time.UTC),
+	}, map[string]*Node{"page0": buildSampleList("foo", "\n", "<bar>")})
+	b.Add(Metadata{
+		ID:      "nb1",
+		Title:   "Ideas & Plans",
+		PageIDs: []string{"page0", "page1"},
+	}, map[string]*Node{"page1": buildSampleList("second page")})
+	assert.Equal(2, b.Len())
+
+	var buf bytes.Buffer
+	err := b.Write(&buf)
+	assert.Nil(err)
+
+	files := readArchive(t, buf.Bytes())
+	assertWellFormed(t, files)
+	assert.Equal("application/epub+zip", files["mimetype"])
+	assert.Contains(files, "META-INF/container.xml")
+
+	opf := files["OEBPS/content.opf"]
+	assert.Contains(opf, "<dc:title>Archive</dc:title>")
+	assert.Contains(opf, "<dc:language>de-DE</dc:language>")
+	assert.Contains(opf, "<meta property=\"dcterms:modified\">2020-10-01T09:30:00Z</meta>")
+	assert.Contains(opf, "<itemref idref=\"chapter-1\"/>\n<itemref idref=\"chapter-2\"/>")
+
+	// notebooks in the root folder come first
+	nav := files["OEBPS/nav.xhtml"]
+	expected := `<ol>
+<li><a href="chapter-1.xhtml">Ideas &amp; Plans</a></li>
+<li><span>Work</span>
+<ol>
+<li><span>Meetings</span>
+<ol>
+<li><a href="chapter-2.xhtml">Standup</a></li>
+</ol>
+</li>
+</ol>
+</li>
+</ol>
+`
+	assert.Contains(nav, expected)
+
+	ch1 := files["OEBPS/chapter-1.xhtml"]
+	assert.Contains(ch1, "<h1>Ideas &amp; Plans</h1>")
+	assert.NotContains(ch1, "Page 1")
+	assert.Contains(ch1, "<section id=\"page-2\">\n<h2>Page 2</h2>\n<p>second page</p>\n</section>")
+
+	ch2 := files["OEBPS/chapter-2.xhtml"]
+	assert.Contains(ch2, "<p>foo<br/>\n&lt;bar&gt;</p>")
+}
+
+func TestComposeEPUB(t *testing.T) {
+	assert := assert.New(t)
+
+	m := Metadata{
+		ID:      "abc",
+		Title:   "My Title",
+		PageIDs: []string{"page0"},
+	}
+	nodes := map[string]*Node{"page0": buildSampleList("foo")}
+
+	var buf bytes.Buffer
+	c := NewEPUBComposer()
+	err := c(&buf, m, nodes)
+	assert.Nil(err)
+
+	z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.Nil(err)
+	assert.Equal(5, len(z.File))
+}
+
+func TestBookError(t *testing.T) {
+	assert := assert.New(t)
+
+	b := NewBook("Archive")
+	err := b.Write(failWriter{})
+	assert.Error(err)
+}
+
+// readArchive reads the files from a zip archive and checks that the mimetype,
+// if present, is the first file and uncompressed.
+func readArchive(t *testing.T, data []byte) map[string]string {
+	assert := assert.New(t)
+
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	assert.Nil(err)
+
+	files := make(map[string]string)
+	for i, f := range z.File {
+		if f.Name == "mimetype" {
+			assert.Equal(0, i)
+			assert.Equal(zip.Store, f.Method)
+		}
+		r, err := f.Open()
+		assert.Nil(err)
+		data, err := ioutil.ReadAll(r)
+		assert.Nil(err)
+		files[f.Name] = string(data)
+	}
+	return files
+}
+
+// assertWellFormed checks that all XML files in an archive can be parsed.
+func assertWellFormed(t *testing.T, files map[string]string) {
+	for name, content := range files {
+		if name == "mimetype" {
+			continue
+		}
+		d := xml.NewDecoder(strings.NewReader(content))
+		for {
+			_, err := d.Token()
+			if err == io.EOF {
+				break
+			}
+			if !assert.Nil(t, err, name) {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package rescript
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/akeil/rmtool/pkg/lines"
+)
+
+// A StrokeFilter tells whether a stroke should be recognized.
+type StrokeFilter func(s lines.Stroke) bool
+
+// MatchBrush creates a stroke filter that accepts strokes drawn with one of the
+// given brush types. The v3 and v5 variants of a brush are treated as equal.
+func MatchBrush(types ...lines.BrushType) StrokeFilter {
+	return func(s lines.Stroke) bool {
+		for _, bt := range types {
+			if brushFamily(s.BrushType) == brushFamily(bt) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MatchColor creates a stroke filter that accepts strokes with one of the
+// given colors.
+func MatchColor(colors ...lines.BrushColor) StrokeFilter {
+	return func(s lines.Stroke) bool {
+		for _, c := range colors {
+			if s.BrushColor == c {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MatchSize creates a stroke filter that accepts strokes with one of the given
+// brush sizes (i.e. the width selected in the toolbar).
+func MatchSize(sizes ...lines.BrushSize) StrokeFilter {
+	return func(s lines.Stroke) bool {
+		for _, size := range sizes {
+			if math.Abs(float64(s.BrushSize-size)) < 0.01 {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// All creates a stroke filter that accepts strokes accepted by all of the
+// given filters.
+func All(filters ...StrokeFilter) StrokeFilter {
+	return func(s lines.Stroke) bool {
+		for _, accept := range filters {
+			if !accept(s) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Not creates a stroke filter that inverts the given filter.
+func Not(f StrokeFilter) StrokeFilter {
+	return func(s lines.Stroke) bool {
+		return !f(s)
+	}
+}
+
+// A StrokeRule describes a stroke filter in a form that can be read from
+// a configuration file.
+//
+// A stroke matches the rule if it matches one of the Brushes, one of the Colors
+// and one of the Sizes. Empty lists match any stroke.
+// If Exclude is set, matching strokes are dropped; otherwise, only matching
+// strokes are recognized.
+//
+// Example: "treat gray pencil as sketches":
+//
+//	StrokeRule{Brushes: []string{"pencil"}, Colors: []string{"gray"}, Exclude: true}
+type StrokeRule struct {
+	Brushes []string `yaml:"brushes"`
+	Colors  []string `yaml:"colors"`
+	Sizes   []string `yaml:"sizes"`
+	Exclude bool     `yaml:"exclude"`
+}
+
+// Filter creates the stroke filter for this rule.
+func (r StrokeRule) Filter() (StrokeFilter, error) {
+	match := make([]StrokeFilter, 0)
+
+	if len(r.Brushes) != 0 {
+		types := make([]lines.BrushType, len(r.Brushes))
+		for i, name := range r.Brushes {
+			bt, ok := brushNames[normalizeName(name)]
+			if !ok {
+				return nil, fmt.Errorf("unknown brush %q", name)
+			}
+			types[i] = bt
+		}
+		match = append(match, MatchBrush(types...))
+	}
+
+	if len(r.Colors) != 0 {
+		colors := make([]lines.BrushColor, len(r.Colors))
+		for i, name := range r.Colors {
+			c, err := parseColor(name)
+			if err != nil {
+				return nil, err
+			}
+			colors[i] = c
+		}
+		match = append(match, MatchColor(colors...))
+	}
+
+	if len(r.Sizes) != 0 {
+		sizes := make([]lines.BrushSize, len(r.Sizes))
+		for i, name := range r.Sizes {
+			size, ok := sizeNames[normalizeName(name)]
+			if !ok {
+				return nil, fmt.Errorf("unknown brush size %q", name)
+			}
+			sizes[i] = size
+		}
+		match = append(match, MatchSize(sizes...))
+	}
+
+	f := All(match...)
+	if r.Exclude {
+		return Not(f), nil
+	}
+	return f, nil
+}
+
+var brushNames = map[string]lines.BrushType{
+	"paintbrush":       lines.PaintBrush,
+	"pencil":           lines.Pencil,
+	"ballpoint":        lines.Ballpoint,
+	"marker":           lines.Marker,
+	"fineliner":        lines.Fineliner,
+	"highlighter":      lines.Highlighter,
+	"mechanicalpencil": lines.MechanicalPencil,
+	"calligraphy":      lines.CalligraphyV5,
+}
+
+var colorNames = map[string]lines.BrushColor{
+	"black": lines.Black,
+	"gray":  lines.Gray,
+	"grey":  lines.Gray,
+	"white": lines.White,
+}
+
+var sizeNames = map[string]lines.BrushSize{
+	"small":  lines.Small,
+	"thin":   lines.Small,
+	"medium": lines.Medium,
+	"large":  lines.Large,
+	"thick":  lines.Large,
+}
+
+// parseColor looks up a color by name. Colors that have no name can be given
+// as the numeric value from the .rm file.
+func parseColor(s string) (lines.BrushColor, error) {
+	c, ok := colorNames[normalizeName(s)]
+	if ok {
+		return c, nil
+	}
+	n, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return c, fmt.Errorf("unknown color %q", s)
+	}
+	return lines.BrushColor(n), nil
+}
+
+func normalizeName(s string) string {
+	s = strings.ToLower(s)
+	s = strings.ReplaceAll(s, "-", "")
+	s = strings.ReplaceAll(s, "_", "")
+	s = strings.ReplaceAll(s, " ", "")
+	return s
+}
+
+// brushFamily maps the v5 variants of a brush type to the v3 variant.
+func brushFamily(bt lines.BrushType) lines.BrushType {
+	switch bt {
+	case lines.PaintBrushV5:
+		return lines.PaintBrush
+	case lines.MechanicalPencilV5:
+		return lines.MechanicalPencil
+	case lines.PencilV5:
+		return lines.Pencil
+	case lines.BallpointV5:
+		return lines.Ballpoint
+	case lines.MarkerV5:
+		return lines.Marker
+	case lines.FinelinerV5:
+		return lines.Fineliner
+	case lines.HighlighterV5:
+		return lines.Highlighter
+	default:
+		return bt
+	}
+}
@@ -0,0 +1,81 @@
+package rescript
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/akeil/rmtool/pkg/lines"
+)
+
+func TestStrokeRule(t *testing.T) {
+	assert := assert.New(t)
+
+	fineliner := lines.Stroke{BrushType: lines.FinelinerV5, BrushColor: lines.Black, BrushSize: lines.Medium}
+	ballpoint := lines.Stroke{BrushType: lines.Ballpoint, BrushColor: lines.Black, BrushSize: lines.Small}
+	pencil := lines.Stroke{BrushType: lines.PencilV5, BrushColor: lines.Gray, BrushSize: lines.Large}
+	blackPencil := lines.Stroke{BrushType: lines.Pencil, BrushColor: lines.Black, BrushSize: lines.Large}
+
+	// only fineliner and ballpoint
+	f, err := StrokeRule{Brushes: []string{"Fineliner", "ballpoint"}}.Filter()
+	assert.Nil(err)
+	assert.True(f(fineliner))
+	assert.True(f(ballpoint))
+	assert.False(f(pencil))
+
+	// gray pencil is a sketch
+	f, err = StrokeRule{Brushes: []string{"pencil"}, Colors: []string{"grey"}, Exclude: true}.Filter()
+	assert.Nil(err)
+	assert.True(f(fineliner))
+	assert.False(f(pencil))
+	assert.True(f(blackPencil))
+
+	// sizes and numeric colors
+	f, err = StrokeRule{Sizes: []string{"thin"}, Colors: []string{"0"}}.Filter()
+	assert.Nil(err)
+	assert.False(f(fineliner))
+	assert.True(f(ballpoint))
+
+	// empty rule matches everything
+	f, err = StrokeRule{}.Filter()
+	assert.Nil(err)
+	assert.True(f(pencil))
+
+	_, err = StrokeRule{Brushes: []string{"crayon"}}.Filter()
+	assert.Error(err)
+	_, err = StrokeRule{Colors: []string{"purple"}}.Filter()
+	assert.Error(err)
+	_, err = StrokeRule{Sizes: []string{"huge"}}.Filter()
+	assert.Error(err)
+}
+
+func TestConvertFilters(t *testing.T) {
+	assert := assert.New(t)
+
+	l := lines.Layer{
+		Strokes: []lines.Stroke{
+			sampleStroke(lines.Fineliner, 100, 100, 200, 100),
+			sampleStroke(lines.Fineliner, 300, 100, 400, 100),
+			sampleStroke(lines.Pencil, 500, 100, 600, 100),
+			sampleStroke(lines.Fineliner, 700, 100, 800, 100),
+		},
+	}
+	l.Strokes[1].BrushColor = lines.Gray
+
+	c := NewConverter()
+	gs, _ := c.ConvertLayer(0, l)
+	// the gray stroke and the pencil have a different pen style
+	assert.Equal(4, len(gs))
+	for _, g := range gs {
+		assert.Equal(1, len(g.Strokes))
+	}
+	assert.Contains(gs[0].PenStyle, "#000000")
+	assert.Contains(gs[1].PenStyle, "#969696")
+	assert.NotEqual(gs[0].PenStyle, gs[2].PenStyle)
+	assert.Equal(gs[0].PenStyle, gs[3].PenStyle)
+
+	c.Filters = []StrokeFilter{Not(MatchBrush(lines.PencilV5))}
+	gs, _ = c.ConvertLayer(0, l)
+	assert.Equal(3, len(allStrokes(gs)))
+	assert.Equal(700, gs[2].Strokes[0].X[0])
+}
@@ -4,6 +4,7 @@ go 1.15
 
 require (
 	github.com/akeil/rmtool v0.0.0-20210103130743-18a76adaa7d3
+	github.com/jung-kurt/gofpdf v1.16.2
 	github.com/stretchr/testify v1.4.0
 	golang.org/x/sync v0.0.0-20201207232520-09787c993a3a
 	gopkg.in/alecthomas/kingpin.v2 v2.2.6
@@ -0,0 +1,182 @@
+package rescript
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/akeil/rmtool/pkg/lines"
+)
+
+// NewHOCRComposer creates a new composer which generates hOCR output,
+// i.e. HTML with the recognized words and their positions on the page.
+//
+// Positions are given in device pixels.
+// See: http://kba.cloud/hocr-spec/1.2/
+func NewHOCRComposer(opts ...ComposeOption) ComposeFunc {
+	o := newComposeOptions(opts)
+	return func(w io.Writer, m Metadata, r map[string]*Node) error {
+		return composeHOCR(w, m, r, o)
+	}
+}
+
+func composeHOCR(w io.Writer, m Metadata, r map[string]*Node, o composeOptions) error {
+	var err error
+	sw := stringWriter{w}
+
+	var b strings.Builder
+	b.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	b.WriteString("<!DOCTYPE html PUBLIC \"-//W3C//DTD XHTML 1.0 Transitional//EN\" \"http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd\">\n")
+	b.WriteString("<html xmlns=\"http://www.w3.org/1999/xhtml\">\n<head>\n")
+	b.WriteString(fmt.Sprintf("<title>%v</title>\n", html.EscapeString(m.Title)))
+	b.WriteString("<meta http-equiv=\"Content-Type\" content=\"text/html; charset=utf-8\" />\n")
+	b.WriteString(fmt.Sprintf("<meta name=\"ocr-system\" content=\"rescript %v\" />\n", Version))
+	b.WriteString("<meta name=\"ocr-capabilities\" content=\"ocr_page ocr_carea ocr_line ocrx_word\" />\n")
+	b.WriteString("</head>\n<body>\n")
+
+	_, err = sw.WriteString(b.String())
+	if err != nil {
+		return err
+	}
+
+	for i, pageID := range m.PageIDs {
+		tail, ok := r[pageID]
+		if ok {
+			err = hocrPage(sw, m.PageNumber(i), o.sections(m, pageID, tail))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = sw.WriteString("</body>\n</html>\n")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func hocrPage(sw io.StringWriter, page int, sections []section) error {
+	var b strings.Builder
+
+	pageBox := BoundingBox{Width: lines.MaxWidth, Height: lines.MaxHeight}
+	b.WriteString(fmt.Sprintf("<div class=\"ocr_page\" id=\"page_%d\" title=\"%v; ppageno %d\">\n", page, bboxTitle(pageBox), page-1))
+
+	lineNo := 0
+	wordNo := 0
+	for i, s := range sections {
+		lns := splitLines(s.tokens)
+		if len(lns) == 0 {
+			continue
+		}
+
+		b.WriteString(fmt.Sprintf("<div class=\"ocr_carea\" id=\"block_%d_%d\"%v>\n", page, i+1, hocrTitle(linesBounds(lns))))
+		for _, line := range lns {
+			lineNo++
+			b.WriteString(fmt.Sprintf("<span class=\"ocr_line\" id=\"line_%d_%d\"%v>", page, lineNo, hocrTitle(wordsBounds(line))))
+			for j, t := range line {
+				wordNo++
+				if j != 0 {
+					b.WriteString(" ")
+				}
+				b.WriteString(fmt.Sprintf("<span class=\"ocrx_word\" id=\"word_%d_%d\"%v>", page, wordNo, hocrTitle(t.Bounds())))
+				b.WriteString(hocrWord(t))
+				b.WriteString("</span>")
+			}
+			b.WriteString("</span>\n")
+		}
+		b.WriteString("</div>\n")
+	}
+
+	b.WriteString("</div>\n")
+
+	_, err := sw.WriteString(b.String())
+	return err
+}
+
+// hocrWord writes the text for a word. If there are alternative candidates,
+// they are added as an "alternatives" element.
+func hocrWord(t *Token) string {
+	alt := alternatives(t)
+	if len(alt) == 0 {
+		return html.EscapeString(t.String())
+	}
+
+	var b strings.Builder
+	b.WriteString("<span class=\"alternatives\">")
+	b.WriteString(fmt.Sprintf("<ins class=\"alt\">%v</ins>", html.EscapeString(t.String())))
+	for _, c := range alt {
+		b.WriteString(fmt.Sprintf("<del class=\"alt\">%v</del>", html.EscapeString(c)))
+	}
+	b.WriteString("</span>")
+	return b.String()
+}
+
+// hocrTitle creates a title attribute with the given bounding box.
+// Returns an empty string if the bounding box is zero.
+func hocrTitle(b BoundingBox) string {
+	if b.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf(" title=\"%v\"", bboxTitle(b))
+}
+
+// bboxTitle formats a bounding box as "bbox x0 y0 x1 y1".
+func bboxTitle(b BoundingBox) string {
+	return fmt.Sprintf("bbox %d %d %d %d",
+		int(math.Round(b.X)),
+		int(math.Round(b.Y)),
+		int(math.Round(b.X+b.Width)),
+		int(math.Round(b.Y+b.Height)))
+}
+
+// splitLines splits a sequence of tokens into lines of words.
+// Whitespace is dropped and lines without words are omitted.
+func splitLines(tokens []*Token) [][]*Token {
+	result := make([][]*Token, 0)
+	line := make([]*Token, 0)
+	for _, t := range tokens {
+		if t.IsNewline() {
+			if len(line) != 0 {
+				result = append(result, line)
+				line = make([]*Token, 0)
+			}
+		} else if !t.IsWhitespace() {
+			line = append(line, t)
+		}
+	}
+	if len(line) != 0 {
+		result = append(result, line)
+	}
+	return result
+}
+
+// alternatives returns the candidates for a token, except the token itself.
+func alternatives(t *Token) []string {
+	alt := make([]string, 0)
+	for _, c := range t.Candidates() {
+		if c != t.String() {
+			alt = append(alt, c)
+		}
+	}
+	return alt
+}
+
+func wordsBounds(tokens []*Token) BoundingBox {
+	var b BoundingBox
+	for _, t := range tokens {
+		b = b.Union(t.Bounds())
+	}
+	return b
+}
+
+func linesBounds(lns [][]*Token) BoundingBox {
+	var b BoundingBox
+	for _, l := range lns {
+		b = b.Union(wordsBounds(l))
+	}
+	return b
+}
@@ -0,0 +1,54 @@
+package rescript
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComposeHOCR(t *testing.T) {
+	assert := assert.New(t)
+
+	m := Metadata{
+		Title:   "My Title",
+		PageIDs: []string{"page0"},
+	}
+	n := buildSampleList("foo", " ", "b&r", "\n", "baz")
+	n.Token().bounds = BoundingBox{X: 100, Y: 100, Width: 120, Height: 50}
+	n.Ahead(2).Token().bounds = BoundingBox{X: 250, Y: 110, Width: 100, Height: 50}
+	n.Ahead(2).Token().candidates = []string{"b&r", "bar"}
+	n.Ahead(4).Token().bounds = BoundingBox{X: 100, Y: 200, Width: 100, Height: 40}
+
+	var buf bytes.Buffer
+	c := NewHOCRComposer()
+	err := c(&buf, m, map[string]*Node{"page0": n})
+	assert.Nil(err)
+
+	out := buf.String()
+	assert.Contains(out, "<title>My Title</title>")
+	assert.Contains(out, `<div class="ocr_page" id="page_1" title="bbox 0 0 1404 1872; ppageno 0">`)
+	assert.Contains(out, `<div class="ocr_carea" id="block_1_1" title="bbox 100 100 350 240">`)
+	assert.Contains(out, `<span class="ocr_line" id="line_1_1" title="bbox 100 100 350 160">`+
+		`<span class="ocrx_word" id="word_1_1" title="bbox 100 100 220 150">foo</span> `+
+		`<span class="ocrx_word" id="word_1_2" title="bbox 250 110 350 160">`+
+		`<span class="alternatives"><ins class="alt">b&amp;r</ins><del class="alt">bar</del></span>`+
+		`</span></span>`)
+	assert.Contains(out, `<span class="ocr_line" id="line_1_2" title="bbox 100 200 200 240">`)
+}
+
+func TestSplitLines(t *testing.T) {
+	assert := assert.New(t)
+
+	tokens := make([]*Token, 0)
+	for _, s := range []string{"\n", "foo", " ", "bar", "\n", "\n", "baz", " "} {
+		tokens = append(tokens, NewToken(s))
+	}
+
+	lns := splitLines(tokens)
+	assert.Equal(2, len(lns))
+	assert.Equal(2, len(lns[0]))
+	assert.Equal("bar", lns[0][1].String())
+	assert.Equal(1, len(lns[1]))
+	assert.Equal("baz", lns[1][0].String())
+}
@@ -0,0 +1,99 @@
+package rescript
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// NewHTMLComposer creates a new composer which generates a standalone HTML
+// document with one section per page and a table of contents.
+//
+// If css is non-empty, it is embedded as a stylesheet.
+func NewHTMLComposer(css string, opts ...ComposeOption) ComposeFunc {
+	o := newComposeOptions(opts)
+	return func(w io.Writer, m Metadata, r map[string]*Node) error {
+		return composeHTML(w, m, r, css, o)
+	}
+}
+
+func composeHTML(w io.Writer, m Metadata, r map[string]*Node, css string, o composeOptions) error {
+	var err error
+	sw := stringWriter{w}
+
+	title := html.EscapeString(m.Title)
+
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n")
+	b.WriteString("<meta charset=\"utf-8\">\n")
+	b.WriteString(fmt.Sprintf("<title>%v</title>\n", title))
+	if css != "" {
+		b.WriteString(fmt.Sprintf("<style>\n%v\n</style>\n", css))
+	}
+	b.WriteString("</head>\n<body>\n")
+	b.WriteString(fmt.Sprintf("<h1>%v</h1>\n", title))
+
+	// table of contents
+	b.WriteString("<nav>\n<ul>\n")
+	for i, pageID := range m.PageIDs {
+		if _, ok := r[pageID]; ok {
+			b.WriteString(fmt.Sprintf("<li><a href=\"#%v\">Page %d</a></li>\n", pageAnchor(m.PageNumber(i)), m.PageNumber(i)))
+		}
+	}
+	b.WriteString("</ul>\n</nav>\n")
+
+	_, err = sw.WriteString(b.String())
+	if err != nil {
+		return err
+	}
+
+	for i, pageID := range m.PageIDs {
+		tail, ok := r[pageID]
+		if ok {
+			err = htmlPage(sw, m.PageNumber(i), o.sections(m, pageID, tail))
+			if err != nil {
+				return err
+			}
+		}
+		// TODO what should we do with pages w/o results?
+	}
+
+	_, err = sw.WriteString("</body>\n</html>\n")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func htmlPage(sw io.StringWriter, number int, sections []section) error {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("<section id=\"%v\">\n", pageAnchor(number)))
+	b.WriteString(fmt.Sprintf("<h2>Page %d</h2>\n", number))
+
+	for _, s := range sections {
+		if s.name != "" {
+			b.WriteString(fmt.Sprintf("<h3>%v</h3>\n", html.EscapeString(s.name)))
+		}
+		b.WriteString("<p>\n")
+		for _, t := range s.tokens {
+			if t.IsNewline() {
+				b.WriteString("<br>\n")
+			} else {
+				b.WriteString(html.EscapeString(t.String()))
+			}
+		}
+		b.WriteString("\n</p>\n")
+	}
+
+	b.WriteString("</section>\n")
+
+	_, err := sw.WriteString(b.String())
+	return err
+}
+
+func pageAnchor(number int) string {
+	return fmt.Sprintf("page-%d", number)
+}
@@ -0,0 +1,80 @@
+package rescript
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComposeHTML(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+
+	m := Metadata{
+		Title:   "Notes <&> Ideas",
+		PageIDs: []string{"page0", "page1", "page2"},
+	}
+
+	nodes := map[string]*Node{
+		"page0": buildSampleList("foo", " ", "<bar>", "\n", "baz"),
+		"page2": buildSampleList("third page"),
+	}
+
+	c := NewHTMLComposer("body { color: black; }")
+	err := c(&buf, m, nodes)
+	assert.Nil(err)
+
+	expected := `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Notes &lt;&amp;&gt; Ideas</title>
+<style>
+body { color: black; }
+</style>
+</head>
+<body>
+<h1>Notes &lt;&amp;&gt; Ideas</h1>
+<nav>
+<ul>
+<li><a href="#page-1">Page 1</a></li>
+<li><a href="#page-3">Page 3</a></li>
+</ul>
+</nav>
+<section id="page-1">
+<h2>Page 1</h2>
+<p>
+foo &lt;bar&gt;<br>
+baz
+</p>
+</section>
+<section id="page-3">
+<h2>Page 3</h2>
+<p>
+third page
+</p>
+</section>
+</body>
+</html>
+`
+	assert.Equal(expected, buf.String())
+
+	// without stylesheet
+	buf.Reset()
+	c = NewHTMLComposer("")
+	err = c(&buf, m, nodes)
+	assert.Nil(err)
+	assert.NotContains(buf.String(), "<style>")
+}
+
+func TestHTMLError(t *testing.T) {
+	assert := assert.New(t)
+
+	node := NewNode(NewToken("foo"))
+	w := failWriter{}
+
+	err := htmlPage(w, 2, composeOptions{}.sections(Metadata{}, "page", node))
+	assert.Error(err)
+}
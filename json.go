@@ -0,0 +1,143 @@
+package rescript
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// NewJSONComposer creates a new composer which generates a JSON document
+// with the document metadata and, for each page, the recognized text
+// with lines and words.
+//
+// Words include their bounding box (in device pixels), alternative
+// candidates and the flags set by pipeline stages.
+func NewJSONComposer(opts ...ComposeOption) ComposeFunc {
+	o := newComposeOptions(opts)
+	return func(w io.Writer, m Metadata, r map[string]*Node) error {
+		return composeJSON(w, m, r, o)
+	}
+}
+
+type jsonDocument struct {
+	ID    string `json:"id,omitempty"`
+	Title string `json:"title"`
+	// Path holds the names of the parent folders.
+	Path      []string   `json:"path"`
+	Modified  string     `json:"modified,omitempty"`
+	Language  string     `json:"language,omitempty"`
+	Tags      []string   `json:"tags,omitempty"`
+	Generator string     `json:"generator"`
+	Pages     []jsonPage `json:"pages"`
+}
+
+type jsonPage struct {
+	ID         string     `json:"id"`
+	Number     int        `json:"number"`
+	Recognized bool       `json:"recognized"`
+	Text       string     `json:"text"`
+	Lines      []jsonLine `json:"lines"`
+}
+
+type jsonLine struct {
+	Text        string       `json:"text"`
+	BoundingBox *BoundingBox `json:"bounding-box,omitempty"`
+	Words       []jsonWord   `json:"words"`
+}
+
+type jsonWord struct {
+	Label       string       `json:"label"`
+	Layer       int          `json:"layer"`
+	BoundingBox *BoundingBox `json:"bounding-box,omitempty"`
+	Candidates  []string     `json:"candidates,omitempty"`
+	Flags       []string     `json:"flags,omitempty"`
+}
+
+func composeJSON(w io.Writer, m Metadata, r map[string]*Node, o composeOptions) error {
+	doc := jsonDocument{
+		ID:        m.ID,
+		Title:     m.Title,
+		Path:      m.Path,
+		Modified:  formatTime(m.Modified),
+		Language:  string(m.Language),
+		Tags:      m.Tags,
+		Generator: "rescript " + Version,
+		Pages:     make([]jsonPage, len(m.PageIDs)),
+	}
+	if doc.Path == nil {
+		doc.Path = make([]string, 0)
+	}
+
+	for i, pageID := range m.PageIDs {
+		p := jsonPage{
+			ID:     pageID,
+			Number: m.PageNumber(i),
+			Lines:  make([]jsonLine, 0),
+		}
+		tail, ok := r[pageID]
+		if ok {
+			p.Recognized = true
+			jsonPageContent(&p, o.sections(m, pageID, tail))
+		}
+		doc.Pages[i] = p
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+func jsonPageContent(p *jsonPage, sections []section) {
+	var text strings.Builder
+	for i, s := range sections {
+		if i != 0 {
+			text.WriteString("\n\n")
+		}
+		for _, t := range s.tokens {
+			text.WriteString(t.String())
+		}
+
+		for _, tokens := range textLines(s.tokens) {
+			line := words(tokens)
+			if len(line) == 0 {
+				continue
+			}
+			l := jsonLine{
+				// the same text as the plaintext composer writes
+				Text:        strings.TrimSpace(lineText(tokens)),
+				BoundingBox: jsonBounds(wordsBounds(line)),
+				Words:       make([]jsonWord, len(line)),
+			}
+			for j, t := range line {
+				l.Words[j] = jsonWord{
+					Label:       t.String(),
+					Layer:       t.Layer(),
+					BoundingBox: jsonBounds(t.Bounds()),
+					Candidates:  t.Candidates(),
+					Flags:       t.Flags(),
+				}
+			}
+			p.Lines = append(p.Lines, l)
+		}
+	}
+	p.Text = text.String()
+}
+
+// words returns the tokens that are not whitespace.
+func words(tokens []*Token) []*Token {
+	w := make([]*Token, 0)
+	for _, t := range tokens {
+		if !t.IsWhitespace() {
+			w = append(w, t)
+		}
+	}
+	return w
+}
+
+// jsonBounds returns nil for a zero bounding box so that it is omitted.
+func jsonBounds(b BoundingBox) *BoundingBox {
+	if b.IsZero() {
+		return nil
+	}
+	return &b
+}
@@ -0,0 +1,70 @@
+package rescript
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComposeJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	m := Metadata{
+		ID:       "doc-id",
+		Title:    "My Title",
+		PageIDs:  []string{"page0", "page1"},
+		Path:     []string{"Work", "Meetings"},
+		Modified: time.Date(2021, 1, 31, 9, 30, 0, 0, time.UTC),
+		Tags:     []string{"todo"},
+	}
+	n := Dehyphenate(buildSampleList("foo", " ", "ba", "-", "\n", "r", "\n", "baz", ",", " ", "qux"))
+	n.Token().bounds = BoundingBox{X: 100, Y: 100, Width: 120, Height: 50}
+	n.Token().candidates = []string{"foo", "fog"}
+
+	var buf bytes.Buffer
+	c := NewJSONComposer()
+	err := c(&buf, m, map[string]*Node{"page0": n})
+	assert.Nil(err)
+
+	var doc jsonDocument
+	err = json.Unmarshal(buf.Bytes(), &doc)
+	assert.Nil(err)
+
+	assert.Equal("doc-id", doc.ID)
+	assert.Equal("My Title", doc.Title)
+	assert.Equal([]string{"Work", "Meetings"}, doc.Path)
+	assert.Equal("2021-01-31T09:30:00Z", doc.Modified)
+	assert.Equal([]string{"todo"}, doc.Tags)
+	assert.Equal(2, len(doc.Pages))
+
+	p := doc.Pages[0]
+	assert.Equal("page0", p.ID)
+	assert.Equal(1, p.Number)
+	assert.True(p.Recognized)
+	assert.Equal("foo bar\nbaz, qux", p.Text)
+	assert.Equal(2, len(p.Lines))
+	assert.Equal("foo bar", p.Lines[0].Text)
+	// no space before punctuation
+	assert.Equal("baz, qux", p.Lines[1].Text)
+	assert.Equal(3, len(p.Lines[1].Words))
+	assert.Equal(BoundingBox{X: 100, Y: 100, Width: 120, Height: 50}, *p.Lines[0].BoundingBox)
+
+	w := p.Lines[0].Words[0]
+	assert.Equal("foo", w.Label)
+	assert.Equal([]string{"foo", "fog"}, w.Candidates)
+	assert.Equal(BoundingBox{X: 100, Y: 100, Width: 120, Height: 50}, *w.BoundingBox)
+
+	w = p.Lines[0].Words[1]
+	assert.Equal("bar", w.Label)
+	assert.Nil(w.BoundingBox)
+	assert.Equal([]string{FlagDehyphenated}, w.Flags)
+
+	// pages without results are included
+	p = doc.Pages[1]
+	assert.Equal("page1", p.ID)
+	assert.False(p.Recognized)
+	assert.Equal(0, len(p.Lines))
+}
@@ -105,6 +105,13 @@ func (n *Node) InsertBefore(o *Node) {
 	}
 }
 
+// join appends the list starting at the given TAIL node to this node.
+// This node must be the HEAD of its list.
+func (n *Node) join(o *Node) {
+	n.next = o
+	o.prev = n
+}
+
 // Update replaces the Token payload for this node with another token.
 func (n *Node) Update(t *Token) {
 	n.data = t
@@ -3,11 +3,18 @@ package rescript
 import (
 	"fmt"
 	"io"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
 )
 
 // NewMarkdownComposer creates a new composer which generates output in markdown format.
-func NewMarkdownComposer() ComposeFunc {
-	return composeMarkdown
+func NewMarkdownComposer(opts ...ComposeOption) ComposeFunc {
+	o := newComposeOptions(opts)
+	return func(w io.Writer, m Metadata, r map[string]*Node) error {
+		return composeMarkdown(w, m, r, o)
+	}
 }
 
 type stringWriter struct {
@@ -18,12 +25,21 @@ func (sw stringWriter) WriteString(s string) (int, error) {
 	return sw.Write([]byte(s))
 }
 
-func composeMarkdown(w io.Writer, m Metadata, r map[string]*Node) error {
+func composeMarkdown(w io.Writer, m Metadata, r map[string]*Node, o composeOptions) error {
 	var err error
 	sw := stringWriter{w}
 
-	// TODO: we might write a yaml frontmatter here
-	sw.WriteString(fmt.Sprintf("# %v\n\n", m.Title))
+	if o.frontMatter {
+		err = writeFrontMatter(sw, m)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = sw.WriteString(fmt.Sprintf("# %v\n\n", m.Title))
+	if err != nil {
+		return err
+	}
 
 	for i, pageID := range m.PageIDs {
 		// thematic break after each page but the last
@@ -36,7 +52,7 @@ func composeMarkdown(w io.Writer, m Metadata, r map[string]*Node) error {
 
 		tail, ok := r[pageID]
 		if ok {
-			err = markdownPage(sw, i, tail)
+			err = markdownPage(sw, m.PageNumber(i), o.sections(m, pageID, tail), o.structure)
 			if err != nil {
 				return err
 			}
@@ -53,28 +69,107 @@ func composeMarkdown(w io.Writer, m Metadata, r map[string]*Node) error {
 	return nil
 }
 
-// "Improve" the result
-// recognize lists:
-// lines starting with "-" or "*"
-// in some cases, add missing space
-// add a newline before the *first* and after the *last* line
-// of consecutive list entries
+// frontMatter holds the fields for the YAML front matter.
+// Empty fields are omitted.
+//
+// Pages is the page count of the notebook; if only some pages are
+// converted, Selected holds their numbers.
+type frontMatter struct {
+	Title     string   `yaml:"title"`
+	ID        string   `yaml:"id,omitempty"`
+	Folder    string   `yaml:"folder,omitempty"`
+	Modified  string   `yaml:"modified,omitempty"`
+	Pages     int      `yaml:"pages"`
+	Selected  []int    `yaml:"selected,flow,omitempty"`
+	Language  string   `yaml:"language,omitempty"`
+	Tags      []string `yaml:"tags,omitempty"`
+	Generator string   `yaml:"generator"`
+}
 
-func markdownPage(sw io.StringWriter, idx int, n *Node) error {
+func writeFrontMatter(sw io.StringWriter, m Metadata) error {
+	fm := frontMatter{
+		Title:     m.Title,
+		ID:        m.ID,
+		Folder:    m.Folder(),
+		Modified:  formatTime(m.Modified),
+		Pages:     m.PageCount(),
+		Selected:  m.PageNumbers,
+		Language:  string(m.Language),
+		Tags:      m.Tags,
+		Generator: "rescript " + Version,
+	}
+
+	data, err := yaml.Marshal(fm)
+	if err != nil {
+		return err
+	}
+
+	_, err = sw.WriteString("---\n" + string(data) + "---\n\n")
+	return err
+}
+
+// formatTime formats a timestamp as RFC 3339.
+// Returns an empty string for the zero time.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func markdownPage(sw io.StringWriter, number int, sections []section, structure bool) error {
 	var err error
 
-	_, err = sw.WriteString(fmt.Sprintf("**Page %d**\n\n", idx+1))
+	_, err = sw.WriteString(fmt.Sprintf("**Page %d**\n\n", number))
 	if err != nil {
 		return err
 	}
 
-	for node := n; node != nil; node = node.Next() {
-		// TODO: we might attempt to "guess" markdown here,
-		_, err = sw.WriteString(node.Token().String())
-		if err != nil {
-			return err
+	for i, s := range sections {
+		if s.name != "" {
+			if i != 0 {
+				_, err = sw.WriteString("\n\n")
+				if err != nil {
+					return err
+				}
+			}
+			_, err = sw.WriteString(fmt.Sprintf("*%v*\n\n", s.name))
+			if err != nil {
+				return err
+			}
+		}
+
+		if structure {
+			_, err = sw.WriteString(markdownStyle.format(inferStructure(s.tokens)))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, t := range s.tokens {
+			_, err = sw.WriteString(t.String())
+			if err != nil {
+				return err
+			}
 		}
 	}
 
 	return nil
 }
+
+// markdownStyle formats blocks of text as markdown.
+// Headings start at level two because the title is the top-level heading.
+var markdownStyle = blockStyle{
+	heading: func(level int) string {
+		return strings.Repeat("#", level+1) + " "
+	},
+	bullet: "- ",
+	number: numbered,
+	task: func(checked bool) string {
+		if checked {
+			return "- [x] "
+		}
+		return "- [ ] "
+	},
+}
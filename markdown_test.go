@@ -3,6 +3,7 @@ package rescript
 import (
 	"bytes"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -54,6 +55,48 @@ func TestMarkdownError(t *testing.T) {
 	node := NewNode(NewToken("foo"))
 	w := failWriter{}
 
-	err := markdownPage(w, 2, node)
+	err := markdownPage(w, 2, composeOptions{}.sections(Metadata{}, "page", node), false)
 	assert.Error(err)
 }
+
+func TestMarkdownFrontMatter(t *testing.T) {
+	assert := assert.New(t)
+
+	m := Metadata{
+		ID:       "abc",
+		Title:    "Title: with colon",
+		PageIDs:  []string{"page0", "page1"},
+		Path:     []string{"Work", "Meetings"},
+		Modified: time.Date(2020, 10, 1, 9, 30, 0, 0, time.UTC),
+		Language: LangEN,
+		Tags:     []string{"meeting"},
+	}
+	nodes := map[string]*Node{"page0": NewNode(NewToken("foo"))}
+
+	var buf bytes.Buffer
+	c := NewMarkdownComposer(FrontMatter())
+	err := c(&buf, m, nodes)
+	assert.Nil(err)
+
+	expected := "---\n" +
+		"title: 'Title: with colon'\n" +
+		"id: abc\n" +
+		"folder: Work/Meetings\n" +
+		"modified: \"2020-10-01T09:30:00Z\"\n" +
+		"pages: 2\n" +
+		"language: en_US\n" +
+		"tags:\n" +
+		"- meeting\n" +
+		"generator: rescript " + Version + "\n" +
+		"---\n\n" +
+		"# Title: with colon\n\n**Page 1**\n\nfoo\n\n---\n\n\n"
+	assert.Equal(expected, buf.String())
+
+	// pages is the page count of the notebook, not of the selection
+	m.PageIDs = []string{"page0", "page1", "page2"}
+	m.Tags = nil
+	buf.Reset()
+	err = c(&buf, m.Subset([]int{2, 3}), nodes)
+	assert.Nil(err)
+	assert.Contains(buf.String(), "pages: 3\nselected: [2, 3]\nlanguage: en_US\n")
+}
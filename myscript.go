@@ -7,8 +7,10 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"time"
 )
 
 const (
@@ -21,6 +23,7 @@ type MyScript struct {
 	host   string
 	client *http.Client
 	sign   func(data []byte) string
+	usage  *Usage
 }
 
 // NewMyScript sets up a new client.
@@ -48,9 +51,22 @@ func NewMyScript(appKey, hmacKey string) *MyScript {
 	}
 }
 
+// TrackUsage makes the client count requests in the given Usage.
+// Requests that would exceed the monthly limit of the Usage fail with
+// ErrLimitExceeded.
+func (m *MyScript) TrackUsage(u *Usage) {
+	m.usage = u
+}
+
 // Batch is the single endpoint fif the ReST API.
 // It performs handwriting recognition.
 func (m *MyScript) Batch(r Request) (Result, error) {
+	return m.batch(r, nil)
+}
+
+// batch sends a request to the batch endpoint.
+// If sent is not nil, it is called once the request went over the wire.
+func (m *MyScript) batch(r Request, sent func()) (Result, error) {
 	var result Result
 	// We need the JSON body as []byte because we need to create a signature over it.
 	payload, err := json.Marshal(r)
@@ -68,6 +84,13 @@ func (m *MyScript) Batch(r Request) (Result, error) {
 		return result, err
 	}
 
+	if m.usage != nil {
+		err = m.usage.reserve(time.Now(), len(payload))
+		if err != nil {
+			return result, err
+		}
+	}
+
 	// MyScript custom headers
 	req.Header.Add("applicationKey", m.appKey)
 	req.Header.Add("hmac", m.sign(payload))
@@ -78,14 +101,20 @@ func (m *MyScript) Batch(r Request) (Result, error) {
 
 	res, err := m.client.Do(req)
 	if err != nil {
+		m.record(0, false)
 		return result, err
 	}
 	defer res.Body.Close()
+	if sent != nil {
+		sent()
+	}
+	body := &countingReader{r: res.Body}
 
 	if res.StatusCode != http.StatusOK {
 		// TODO: Error Model
 		e := make(map[string]interface{})
-		err = json.NewDecoder(res.Body).Decode(&e)
+		err = json.NewDecoder(body).Decode(&e)
+		m.record(body.n, false)
 		if err != nil {
 			return result, err
 		}
@@ -93,7 +122,8 @@ func (m *MyScript) Batch(r Request) (Result, error) {
 		return result, fmt.Errorf("bad status code %v", res.StatusCode)
 	}
 
-	err = json.NewDecoder(res.Body).Decode(&result)
+	err = json.NewDecoder(body).Decode(&result)
+	m.record(body.n, err == nil)
 	if err != nil {
 		return result, err
 	}
@@ -101,6 +131,26 @@ func (m *MyScript) Batch(r Request) (Result, error) {
 	return result, nil
 }
 
+// record counts a response, if usage is tracked.
+func (m *MyScript) record(size int64, ok bool) {
+	if m.usage != nil {
+		// the request was sent, failing to save the count is not an error
+		m.usage.record(time.Now(), size, ok)
+	}
+}
+
+// countingReader counts the bytes that are read.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
 func (m *MyScript) resolveEndpoint(ep string) (*url.URL, error) {
 	base, err := url.Parse(m.host)
 	if err != nil {
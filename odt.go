@@ -0,0 +1,165 @@
+package rescript
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const odtNamespaces = ` xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0"` +
+	` xmlns:style="urn:oasis:names:tc:opendocument:xmlns:style:1.0"` +
+	` xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0"` +
+	` xmlns:fo="urn:oasis:names:tc:opendocument:xmlns:xsl-fo-compatible:1.0"` +
+	` xmlns:meta="urn:oasis:names:tc:opendocument:xmlns:meta:1.0"` +
+	` xmlns:dc="http://purl.org/dc/elements/1.1/"` +
+	` office:version="1.2"`
+
+// NewODTComposer creates a composer which generates an OpenDocument text
+// file (.odt), e.g. for LibreOffice.
+//
+// The layout is the same as for DOCX output.
+func NewODTComposer(opts ...ComposeOption) ComposeFunc {
+	o := newComposeOptions(opts)
+	return func(w io.Writer, m Metadata, r map[string]*Node) error {
+		return composeODT(w, m, r, o)
+	}
+}
+
+func composeODT(w io.Writer, m Metadata, r map[string]*Node, o composeOptions) error {
+	files := []archiveFile{
+		{"META-INF/manifest.xml", odtManifest},
+		{"meta.xml", odtMeta(m)},
+		{"styles.xml", odtStyles},
+		{"content.xml", odtContent(m, officePages(m, r, o))},
+	}
+
+	return writeArchive(w, "application/vnd.oasis.opendocument.text", files)
+}
+
+func odtContent(m Metadata, pages []officePage) string {
+	var b strings.Builder
+	b.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	b.WriteString(fmt.Sprintf("<office:document-content%v>\n", odtNamespaces))
+	b.WriteString(odtAutomaticStyles)
+	b.WriteString("<office:body>\n<office:text>\n")
+	b.WriteString(fmt.Sprintf("<text:p text:style-name=\"Title\">%v</text:p>\n", escapeXML(m.Title)))
+
+	for i, p := range pages {
+		// each page but the first starts on a new page
+		style := "Heading_20_1"
+		if i != 0 {
+			style = "PageBreak"
+		}
+		b.WriteString(odtHeading(style, 1, fmt.Sprintf("Page %d", p.number)))
+
+		for _, s := range p.sections {
+			depth := 1
+			if s.name != "" {
+				depth = 2
+				b.WriteString(odtHeading("Heading_20_2", 2, s.name))
+			}
+			odtBlocks(&b, s.blocks, depth)
+		}
+	}
+
+	b.WriteString("</office:text>\n</office:body>\n</office:document-content>\n")
+	return b.String()
+}
+
+func odtBlocks(b *strings.Builder, blocks []textBlock, depth int) {
+	for i, blk := range blocks {
+		// close the previous list
+		if i != 0 && blocks[i-1].isListItem() && !blk.continues(blocks[i-1]) {
+			b.WriteString("</text:list>\n")
+		}
+		// open a new list
+		if blk.isListItem() && (i == 0 || !blk.continues(blocks[i-1])) {
+			style := "Bullets"
+			if blk.kind == numberedItem {
+				style = "Numbers"
+			}
+			b.WriteString(fmt.Sprintf("<text:list text:style-name=\"%v\">\n", style))
+		}
+
+		switch blk.kind {
+		case heading:
+			level := headingLevel(depth, blk.level)
+			b.WriteString(odtHeading(fmt.Sprintf("Heading_20_%d", level), level, blk.text()))
+		case bulletItem:
+			b.WriteString("<text:list-item>" + odtParagraph(blk.lines) + "</text:list-item>\n")
+		case taskItem:
+			b.WriteString("<text:list-item>" + odtParagraph([]string{taskMark(blk.checked) + blk.text()}) + "</text:list-item>\n")
+		case numberedItem:
+			start := ""
+			if i == 0 || !blk.continues(blocks[i-1]) {
+				start = fmt.Sprintf(" text:start-value=\"%d\"", blk.number)
+			}
+			b.WriteString(fmt.Sprintf("<text:list-item%v>%v</text:list-item>\n", start, odtParagraph(blk.lines)))
+		default:
+			b.WriteString(odtParagraph(blk.lines) + "\n")
+		}
+	}
+
+	if len(blocks) != 0 && blocks[len(blocks)-1].isListItem() {
+		b.WriteString("</text:list>\n")
+	}
+}
+
+func odtHeading(style string, level int, text string) string {
+	return fmt.Sprintf("<text:h text:style-name=\"%v\" text:outline-level=\"%d\">%v</text:h>\n", style, level, escapeXML(text))
+}
+
+// odtParagraph creates a paragraph, lines are separated by line breaks.
+func odtParagraph(lines []string) string {
+	escaped := make([]string, len(lines))
+	for i, line := range lines {
+		escaped[i] = escapeXML(line)
+	}
+	return "<text:p text:style-name=\"Standard\">" + strings.Join(escaped, "<text:line-break/>") + "</text:p>"
+}
+
+func odtMeta(m Metadata) string {
+	var b strings.Builder
+	b.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	b.WriteString(fmt.Sprintf("<office:document-meta%v>\n<office:meta>\n", odtNamespaces))
+	b.WriteString(fmt.Sprintf("<meta:generator>rescript %v</meta:generator>\n", Version))
+	b.WriteString(fmt.Sprintf("<dc:title>%v</dc:title>\n", escapeXML(m.Title)))
+	if m.Language != "" {
+		b.WriteString(fmt.Sprintf("<dc:language>%v</dc:language>\n", strings.ReplaceAll(string(m.Language), "_", "-")))
+	}
+	if !m.Modified.IsZero() {
+		b.WriteString(fmt.Sprintf("<dc:date>%v</dc:date>\n", m.Modified.UTC().Format("2006-01-02T15:04:05")))
+	}
+	b.WriteString("</office:meta>\n</office:document-meta>\n")
+	return b.String()
+}
+
+const odtManifest = `<?xml version="1.0" encoding="UTF-8"?>
+<manifest:manifest xmlns:manifest="urn:oasis:names:tc:opendocument:xmlns:manifest:1.0" manifest:version="1.2">
+<manifest:file-entry manifest:full-path="/" manifest:media-type="application/vnd.oasis.opendocument.text"/>
+<manifest:file-entry manifest:full-path="content.xml" manifest:media-type="text/xml"/>
+<manifest:file-entry manifest:full-path="styles.xml" manifest:media-type="text/xml"/>
+<manifest:file-entry manifest:full-path="meta.xml" manifest:media-type="text/xml"/>
+</manifest:manifest>
+`
+
+const odtAutomaticStyles = `<office:automatic-styles>
+<style:style style:name="PageBreak" style:family="paragraph" style:parent-style-name="Heading_20_1"><style:paragraph-properties fo:break-before="page"/></style:style>
+<text:list-style style:name="Bullets"><text:list-level-style-bullet text:level="1" text:bullet-char="•"><style:list-level-properties text:space-before="0.25in" text:min-label-width="0.25in"/></text:list-level-style-bullet></text:list-style>
+<text:list-style style:name="Numbers"><text:list-level-style-number text:level="1" style:num-suffix="." style:num-format="1"><style:list-level-properties text:space-before="0.25in" text:min-label-width="0.25in"/></text:list-level-style-number></text:list-style>
+</office:automatic-styles>
+`
+
+const odtStyles = `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-styles xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:style="urn:oasis:names:tc:opendocument:xmlns:style:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0" xmlns:fo="urn:oasis:names:tc:opendocument:xmlns:xsl-fo-compatible:1.0" office:version="1.2">
+<office:styles>
+<style:style style:name="Standard" style:family="paragraph"><style:paragraph-properties fo:margin-bottom="0.1in"/><style:text-properties fo:font-size="11pt"/></style:style>
+<style:style style:name="Title" style:family="paragraph" style:parent-style-name="Standard"><style:text-properties fo:font-size="28pt"/></style:style>
+<style:style style:name="Heading" style:family="paragraph" style:parent-style-name="Standard"><style:paragraph-properties fo:margin-top="0.17in" fo:keep-with-next="always"/><style:text-properties fo:font-weight="bold"/></style:style>
+<style:style style:name="Heading_20_1" style:display-name="Heading 1" style:family="paragraph" style:parent-style-name="Heading" style:default-outline-level="1"><style:text-properties fo:font-size="16pt"/></style:style>
+<style:style style:name="Heading_20_2" style:display-name="Heading 2" style:family="paragraph" style:parent-style-name="Heading" style:default-outline-level="2"><style:text-properties fo:font-size="14pt"/></style:style>
+<style:style style:name="Heading_20_3" style:display-name="Heading 3" style:family="paragraph" style:parent-style-name="Heading" style:default-outline-level="3"><style:text-properties fo:font-size="12pt"/></style:style>
+<style:style style:name="Heading_20_4" style:display-name="Heading 4" style:family="paragraph" style:parent-style-name="Heading" style:default-outline-level="4"><style:text-properties fo:font-size="11pt" fo:font-style="italic"/></style:style>
+</office:styles>
+</office:document-styles>
+`
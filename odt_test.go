@@ -0,0 +1,55 @@
+package rescript
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComposeODT(t *testing.T) {
+	assert := assert.New(t)
+
+	m, nodes := officeSample()
+
+	var buf bytes.Buffer
+	c := NewODTComposer(InferStructure())
+	err := c(&buf, m, nodes)
+	assert.Nil(err)
+
+	files := readArchive(t, buf.Bytes())
+	assertWellFormed(t, files)
+	assert.Equal("application/vnd.oasis.opendocument.text", files["mimetype"])
+	assert.Contains(files, "META-INF/manifest.xml")
+	assert.Contains(files["meta.xml"], "<dc:title>Notes &amp; Ideas</dc:title>")
+
+	expected := `<text:p text:style-name="Title">Notes &amp; Ideas</text:p>
+<text:h text:style-name="Heading_20_1" text:outline-level="1">Page 1</text:h>
+<text:h text:style-name="Heading_20_2" text:outline-level="2">Agenda</text:h>
+<text:list text:style-name="Bullets">
+<text:list-item><text:p text:style-name="Standard">one</text:p></text:list-item>
+<text:list-item><text:p text:style-name="Standard">☒ two</text:p></text:list-item>
+</text:list>
+<text:p text:style-name="Standard">some<text:line-break/>&lt;text&gt;</text:p>
+<text:h text:style-name="PageBreak" text:outline-level="1">Page 3</text:h>
+<text:list text:style-name="Numbers">
+<text:list-item text:start-value="3"><text:p text:style-name="Standard">three</text:p></text:list-item>
+<text:list-item><text:p text:style-name="Standard">four</text:p></text:list-item>
+</text:list>
+`
+	assert.Contains(files["content.xml"], expected)
+}
+
+func TestODTPlain(t *testing.T) {
+	assert := assert.New(t)
+
+	m, nodes := officeSample()
+
+	var buf bytes.Buffer
+	err := NewODTComposer()(&buf, m, nodes)
+	assert.Nil(err)
+
+	files := readArchive(t, buf.Bytes())
+	assert.Contains(files["content.xml"], `<text:p text:style-name="Standard">Agenda<text:line-break/>===<text:line-break/>- one<text:line-break/>[x] two</text:p>`)
+	assert.NotContains(files["content.xml"], "<text:list ")
+}
@@ -0,0 +1,71 @@
+package rescript
+
+import (
+	"bytes"
+	"encoding/xml"
+)
+
+// maxHeading is the deepest heading level used in office documents.
+const maxHeading = 4
+
+// An officePage holds the content of a page for office documents,
+// i.e. DOCX or ODT.
+type officePage struct {
+	number   int
+	sections []officeSection
+}
+
+type officeSection struct {
+	name   string
+	blocks []textBlock
+}
+
+// officePages collects the recognized pages with their blocks of text.
+// Pages without results are skipped.
+func officePages(m Metadata, r map[string]*Node, o composeOptions) []officePage {
+	pages := make([]officePage, 0)
+	for i, pageID := range m.PageIDs {
+		tail, ok := r[pageID]
+		if !ok {
+			continue
+		}
+
+		p := officePage{number: m.PageNumber(i), sections: make([]officeSection, 0)}
+		for _, s := range o.sections(m, pageID, tail) {
+			var blocks []textBlock
+			if o.structure {
+				blocks = inferStructure(s.tokens)
+			} else {
+				blocks = paragraphs(s.tokens)
+			}
+			p.sections = append(p.sections, officeSection{name: s.name, blocks: blocks})
+		}
+		pages = append(pages, p)
+	}
+	return pages
+}
+
+// headingLevel returns the outline level for a heading at the given depth
+// below a page or section heading.
+func headingLevel(depth, level int) int {
+	l := depth + level
+	if l > maxHeading {
+		return maxHeading
+	}
+	return l
+}
+
+// taskMark returns a checkbox symbol for a task item.
+func taskMark(checked bool) string {
+	if checked {
+		return "☒ "
+	}
+	return "☐ "
+}
+
+// escapeXML escapes a string for use in XML text and attribute values.
+func escapeXML(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
@@ -0,0 +1,99 @@
+package rescript
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// NewOrgComposer creates a new composer which generates output in Emacs
+// Org mode format.
+//
+// Each page is a top-level heading with a property drawer that holds the
+// page ID and page number.
+func NewOrgComposer(opts ...ComposeOption) ComposeFunc {
+	o := newComposeOptions(opts)
+	return func(w io.Writer, m Metadata, r map[string]*Node) error {
+		return composeOrg(w, m, r, o)
+	}
+}
+
+func composeOrg(w io.Writer, m Metadata, r map[string]*Node, o composeOptions) error {
+	var err error
+	sw := stringWriter{w}
+
+	_, err = sw.WriteString(fmt.Sprintf("#+TITLE: %v\n", m.Title))
+	if err != nil {
+		return err
+	}
+
+	for i, pageID := range m.PageIDs {
+		tail, ok := r[pageID]
+		if ok {
+			err = orgPage(sw, m.PageNumber(i), pageID, o.sections(m, pageID, tail), o.structure)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func orgPage(sw io.StringWriter, number int, pageID string, sections []section, structure bool) error {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("\n* Page %d\n", number))
+	b.WriteString(":PROPERTIES:\n")
+	b.WriteString(fmt.Sprintf(":PAGE_ID: %v\n", pageID))
+	b.WriteString(fmt.Sprintf(":PAGE_NUMBER: %d\n", number))
+	b.WriteString(":END:\n")
+
+	for _, s := range sections {
+		depth := 1
+		if s.name != "" {
+			depth = 2
+			b.WriteString(fmt.Sprintf("\n** %v\n", s.name))
+		}
+
+		text := orgStyle(depth).text(s.tokens, structure)
+		if text != "" {
+			b.WriteString("\n" + text + "\n")
+		}
+	}
+
+	_, err := sw.WriteString(b.String())
+	return err
+}
+
+// orgStyle formats blocks of text for Org mode.
+// Headings are placed one level below the given depth,
+// because Org mode does not allow to skip levels.
+func orgStyle(depth int) blockStyle {
+	return blockStyle{
+		heading: func(level int) string {
+			return strings.Repeat("*", depth+1) + " "
+		},
+		bullet: "- ",
+		number: numbered,
+		task: func(checked bool) string {
+			if checked {
+				return "- [X] "
+			}
+			return "- [ ] "
+		},
+		escape: orgEscape,
+	}
+}
+
+var orgHeadingRx = regexp.MustCompile(`^\*+(\s|$)`)
+
+// orgEscape keeps a line that starts like a heading from becoming one
+// by adding a zero width space in front of it.
+func orgEscape(line string) string {
+	if orgHeadingRx.MatchString(line) {
+		return "\u200b" + line
+	}
+	return line
+}
@@ -0,0 +1,72 @@
+package rescript
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComposeOrg(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+
+	m := Metadata{
+		Title:   "My Title",
+		PageIDs: []string{"page0", "page1", "page2"},
+	}
+
+	nodes := map[string]*Node{
+		"page0": buildSampleList("foo", " ", "bar", "\n", "baz", "\n"),
+		"page2": buildSampleList("Todo", "\n", "---", "\n", "[x]", " ", "done", "\n", "-", " ", "open"),
+	}
+
+	c := NewOrgComposer(InferStructure())
+	err := c(&buf, m, nodes)
+	assert.Nil(err)
+
+	expected := `#+TITLE: My Title
+
+* Page 1
+:PROPERTIES:
+:PAGE_ID: page0
+:PAGE_NUMBER: 1
+:END:
+
+foo bar
+baz
+
+* Page 3
+:PROPERTIES:
+:PAGE_ID: page2
+:PAGE_NUMBER: 3
+:END:
+
+** Todo
+
+- [X] done
+- open
+`
+	assert.Equal(expected, buf.String())
+}
+
+func TestOrgEscape(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	node := buildSampleList("*", " ", "not", " ", "a", " ", "heading", "\n", "*bold*")
+	err := orgPage(&buf, 1, "page", composeOptions{}.sections(Metadata{}, "page", node), false)
+	assert.Nil(err)
+	assert.Contains(buf.String(), "\n\u200b* not a heading\n*bold*\n")
+}
+
+func TestOrgError(t *testing.T) {
+	assert := assert.New(t)
+
+	node := NewNode(NewToken("foo"))
+	w := failWriter{}
+
+	err := orgPage(w, 2, "page", composeOptions{}.sections(Metadata{}, "page", node), false)
+	assert.Error(err)
+}
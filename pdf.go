@@ -0,0 +1,134 @@
+package rescript
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/jung-kurt/gofpdf"
+
+	"github.com/akeil/rmtool/pkg/lines"
+	"github.com/akeil/rmtool/pkg/render"
+)
+
+const (
+	pointsPerInch = 72.0
+	// text rendering mode for invisible text
+	invisibleText = 3
+)
+
+// pageRenderer paints the page with the given ID as a PNG image.
+type pageRenderer func(pageID string, w io.Writer) error
+
+// NewPDFComposer creates a composer which renders the handwriting from each
+// page into a PDF file and places the recognized words as an invisible text
+// layer on top of it. This makes the PDF searchable and the text selectable.
+//
+// The composer requires the Metadata to be created with NewMetadata because
+// it needs access to the document for rendering.
+// The given render Context is used to paint the handwriting.
+//
+// Text from layers given with ExcludeLayers is left out of the text layer.
+// The other options do not apply because the text is placed on the
+// handwriting, not laid out.
+func NewPDFComposer(c *render.Context, opts ...ComposeOption) ComposeFunc {
+	o := newComposeOptions(opts)
+	return func(w io.Writer, m Metadata, r map[string]*Node) error {
+		if m.doc == nil {
+			return fmt.Errorf("PDF output requires the document")
+		}
+		page := func(pageID string, w io.Writer) error {
+			return c.Page(m.doc, pageID, w)
+		}
+		return composePDF(w, m, r, page, o)
+	}
+}
+
+func composePDF(w io.Writer, m Metadata, r map[string]*Node, page pageRenderer, o composeOptions) error {
+	// pages have the size and aspect ratio of the device screen
+	width := toPoints(lines.MaxWidth)
+	height := toPoints(lines.MaxHeight)
+	pdf := gofpdf.NewCustom(&gofpdf.InitType{
+		OrientationStr: "P",
+		UnitStr:        "pt",
+		Size:           gofpdf.SizeType{Wd: width, Ht: height},
+	})
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetTitle(m.Title, true)
+	pdf.SetProducer("rescript "+Version, true)
+	pdf.SetFont("helvetica", "", 12)
+
+	// the core fonts do not support unicode
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	for i, pageID := range m.PageIDs {
+		pdf.AddPage()
+
+		var buf bytes.Buffer
+		err := page(pageID, &buf)
+		if err != nil {
+			return err
+		}
+		name := fmt.Sprintf("page-%d", i)
+		opts := gofpdf.ImageOptions{ImageType: "PNG"}
+		pdf.RegisterImageOptionsReader(name, opts, &buf)
+		pdf.ImageOptions(name, 0, 0, width, height, false, opts, 0, "")
+
+		tail, ok := r[pageID]
+		if ok {
+			pdfTextLayer(pdf, o.sections(m, pageID, tail), tr)
+		}
+
+		if pdf.Err() {
+			return pdf.Error()
+		}
+	}
+
+	return pdf.Output(w)
+}
+
+// pdfTextLayer places the tokens as invisible text on the current page.
+//
+// Each word is scaled to fit its bounding box.
+// Tokens without a bounding box are skipped.
+func pdfTextLayer(pdf *gofpdf.Fpdf, sections []section, tr func(string) string) {
+	pdf.SetTextRenderingMode(invisibleText)
+	defer pdf.SetTextRenderingMode(0)
+
+	for _, s := range sections {
+		for _, t := range s.tokens {
+			pdfWord(pdf, t, tr)
+		}
+	}
+}
+
+// pdfWord places a single token on the current page.
+func pdfWord(pdf *gofpdf.Fpdf, t *Token, tr func(string) string) {
+	b := t.Bounds()
+	if b.IsZero() || t.IsWhitespace() {
+		return
+	}
+
+	s := tr(t.String())
+	x := toPoints(b.X)
+	w := toPoints(b.Width)
+	h := toPoints(b.Height)
+	// the baseline is at the bottom of the bounding box
+	y := toPoints(b.Y + b.Height)
+
+	// use the height of the bounding box as font size,
+	// make the text smaller if it is too wide
+	pdf.SetFontSize(h)
+	sw := pdf.GetStringWidth(s)
+	if sw > w && sw > 0 {
+		pdf.SetFontSize(h * w / sw)
+	}
+
+	pdf.Text(x, y, s)
+}
+
+// toPoints converts device pixels to PDF points.
+func toPoints(px float64) float64 {
+	return px * pointsPerInch / DeviceResolution
+}
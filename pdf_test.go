@@ -0,0 +1,74 @@
+package rescript
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io"
+	"testing"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComposePDF(t *testing.T) {
+	assert := assert.New(t)
+
+	m := Metadata{
+		Title:   "My Title",
+		PageIDs: []string{"page0", "page1"},
+	}
+	nodes := map[string]*Node{
+		"page0": sampleWords(),
+	}
+
+	rendered := make([]string, 0)
+	page := func(pageID string, w io.Writer) error {
+		rendered = append(rendered, pageID)
+		return png.Encode(w, image.NewRGBA(image.Rect(0, 0, 10, 10)))
+	}
+
+	var buf bytes.Buffer
+	err := composePDF(&buf, m, nodes, page, composeOptions{})
+	assert.Nil(err)
+	assert.Equal([]string{"page0", "page1"}, rendered)
+	assert.True(bytes.HasPrefix(buf.Bytes(), []byte("%PDF")))
+
+	// without a document, the composer cannot render pages
+	c := NewPDFComposer(nil)
+	err = c(&buf, m, nodes)
+	assert.Error(err)
+}
+
+func TestPDFTextLayer(t *testing.T) {
+	assert := assert.New(t)
+
+	pdf := gofpdf.New("P", "pt", "A4", "")
+	pdf.SetCompression(false)
+	pdf.SetFont("helvetica", "", 12)
+	pdf.AddPage()
+
+	words := sampleWords()
+	words.Ahead(2).Token().layer = 1
+	o := newComposeOptions([]ComposeOption{ExcludeLayers("Layer 1")})
+	pdfTextLayer(pdf, o.sections(Metadata{}, "page", words), pdf.UnicodeTranslatorFromDescriptor(""))
+
+	var buf bytes.Buffer
+	err := pdf.Output(&buf)
+	assert.Nil(err)
+
+	out := buf.String()
+	assert.Contains(out, "3 Tr")
+	assert.Contains(out, "(bar) Tj")
+	// excluded layer
+	assert.NotContains(out, "(foo) Tj")
+	// no bounding box
+	assert.NotContains(out, "(baz) Tj")
+}
+
+func sampleWords() *Node {
+	n := buildSampleList("foo", " ", "bar", " ", "baz")
+	n.Token().bounds = BoundingBox{X: 100, Y: 100, Width: 120, Height: 50}
+	n.Ahead(2).Token().bounds = BoundingBox{X: 250, Y: 100, Width: 120, Height: 50}
+	return n
+}
@@ -6,6 +6,9 @@ package rescript
 // or insert tokens.
 type PipelineFunc func(n *Node) *Node
 
+// FlagDehyphenated marks words which were merged by Dehyphenate.
+const FlagDehyphenated = "dehyphenated"
+
 // BuildPipeline combines several pipeline functions into one.
 func BuildPipeline(p ...PipelineFunc) PipelineFunc {
 	return func(n *Node) *Node {
@@ -62,18 +65,25 @@ func Dehyphenate(n *Node) *Node {
 			start := node.Behind(count)
 			// this will become the merged word
 			s := start.Token().String()
+			b := start.Token().Bounds()
 
 			// drop `count` following nodes
 			for i := 0; i < count; i++ {
 				next := start.Next()
 				if next.Token().IsWord() {
 					s += next.Token().String()
+					b = b.Union(next.Token().Bounds())
 				}
 				next.Remove()
 			}
 
 			// make the merged word part of the list
-			start.Update(NewToken(s))
+			merged := start.Token().withText(s)
+			merged.bounds = b
+			// candidates refer to the parts, not to the merged word
+			merged.candidates = nil
+			merged.AddFlag(FlagDehyphenated)
+			start.Update(merged)
 
 			// "fix" the iterator - we have dropped the current node, reset it
 			node = start
@@ -8,11 +8,14 @@ import (
 
 // NewPlaintextComposer creates a new composer which creates plain text output
 // for a regicnition result.
-func NewPlaintextComposer() ComposeFunc {
-	return composePlain
+func NewPlaintextComposer(opts ...ComposeOption) ComposeFunc {
+	o := newComposeOptions(opts)
+	return func(w io.Writer, m Metadata, r map[string]*Node) error {
+		return composePlain(w, m, r, o)
+	}
 }
 
-func composePlain(w io.Writer, m Metadata, r map[string]*Node) error {
+func composePlain(w io.Writer, m Metadata, r map[string]*Node, o composeOptions) error {
 	var err error
 	sw := stringWriter{w}
 
@@ -29,7 +32,7 @@ func composePlain(w io.Writer, m Metadata, r map[string]*Node) error {
 	for i, pageID := range m.PageIDs {
 		tail, ok := r[pageID]
 		if ok {
-			err = plaintextPage(sw, i, tail)
+			err = plaintextPage(sw, m.PageNumber(i), o.sections(m, pageID, tail))
 			if err != nil {
 				return err
 			}
@@ -40,18 +43,33 @@ func composePlain(w io.Writer, m Metadata, r map[string]*Node) error {
 	return nil
 }
 
-func plaintextPage(sw io.StringWriter, idx int, n *Node) error {
+func plaintextPage(sw io.StringWriter, number int, sections []section) error {
 	var err error
 
-	_, err = sw.WriteString(fmt.Sprintf("\n[Page %d]\n\n", idx+1))
+	_, err = sw.WriteString(fmt.Sprintf("\n[Page %d]\n\n", number))
 	if err != nil {
 		return err
 	}
 
-	for node := n; node != nil; node = node.Next() {
-		_, err = sw.WriteString(node.Token().String())
-		if err != nil {
-			return err
+	for i, s := range sections {
+		if s.name != "" {
+			if i != 0 {
+				_, err = sw.WriteString("\n\n")
+				if err != nil {
+					return err
+				}
+			}
+			_, err = sw.WriteString(fmt.Sprintf("[%v]\n\n", s.name))
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, t := range s.tokens {
+			_, err = sw.WriteString(t.String())
+			if err != nil {
+				return err
+			}
 		}
 	}
 
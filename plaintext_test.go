@@ -55,7 +55,7 @@ func TestPlaintextError(t *testing.T) {
 	node := NewNode(NewToken("foo"))
 	w := failWriter{}
 
-	err := plaintextPage(w, 2, node)
+	err := plaintextPage(w, 2, composeOptions{}.sections(Metadata{}, "page", node))
 	assert.Error(err)
 }
 
@@ -64,3 +64,59 @@ type failWriter struct{}
 func (f failWriter) WriteString(s string) (int, error) {
 	return 0, errors.New("test failure")
 }
+
+func (f failWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("test failure")
+}
+
+func TestPlaintextLayers(t *testing.T) {
+	assert := assert.New(t)
+
+	node := buildSampleList("foo", " ", "bar", "\n", "note")
+	for n := node.Ahead(3); n != nil; n = n.Next() {
+		n.Token().layer = 1
+	}
+	node.Ahead(3).Token().layer = 0
+
+	m := Metadata{
+		Title:   "Layers",
+		PageIDs: []string{"page0"},
+		Layers: map[string][]string{
+			"page0": []string{"Text", "Notes"},
+		},
+	}
+	nodes := map[string]*Node{"page0": node}
+
+	var buf bytes.Buffer
+	c := NewPlaintextComposer(SplitLayers())
+	err := c(&buf, m, nodes)
+	assert.Nil(err)
+	expected := "LAYERS\n\n[Page 1]\n\n[Text]\n\nfoo bar\n\n[Notes]\n\nnote\n"
+	assert.Equal(expected, buf.String())
+
+	buf.Reset()
+	c = NewPlaintextComposer(ExcludeLayers("notes"))
+	err = c(&buf, m, nodes)
+	assert.Nil(err)
+	expected = "LAYERS\n\n[Page 1]\n\nfoo bar\n\n"
+	assert.Equal(expected, buf.String())
+}
+
+func TestPlaintextSubset(t *testing.T) {
+	assert := assert.New(t)
+
+	m := Metadata{
+		Title:   "My Title",
+		PageIDs: []string{"page0", "page1", "page2"},
+	}
+	m = m.Subset([]int{3})
+
+	nodes := map[string]*Node{
+		"page2": NewNode(NewToken("last page")),
+	}
+
+	var buf bytes.Buffer
+	err := NewPlaintextComposer()(&buf, m, nodes)
+	assert.Nil(err)
+	assert.Equal("MY TITLE\n\n[Page 3]\n\nlast page\n", buf.String())
+}
@@ -0,0 +1,89 @@
+package rescript
+
+import (
+	"time"
+)
+
+// EventType tells what happened in a progress Event.
+type EventType int
+
+// Progress events, in the order in which they occur for a document.
+const (
+	// DocumentStarted is sent when recognition for a document starts.
+	DocumentStarted EventType = iota
+	// PageLoaded is sent when the drawing for a page was read.
+	PageLoaded
+	// CacheHit is sent for each request that is answered from the cache.
+	CacheHit
+	// RequestSent is sent for each request to the MyScript API,
+	// once it was sent; not for requests refused before, e.g. by the usage
+	// limit.
+	RequestSent
+	// PageDone is sent when a page was recognized.
+	PageDone
+	// DocumentDone is sent when all pages of a document were recognized.
+	DocumentDone
+	// Error is sent if recognition for a page or document failed.
+	Error
+)
+
+var eventNames = map[EventType]string{
+	DocumentStarted: "document-started",
+	PageLoaded:      "page-loaded",
+	CacheHit:        "cache-hit",
+	RequestSent:     "request-sent",
+	PageDone:        "page-done",
+	DocumentDone:    "document-done",
+	Error:           "error",
+}
+
+func (t EventType) String() string {
+	return eventNames[t]
+}
+
+// An Event reports the progress of a call to Recognize.
+type Event struct {
+	Type EventType
+	Time time.Time
+	// Document is the ID of the document, Title its name.
+	Document string
+	Title    string
+	// PageID and Page (the page number, starting at 1) are set
+	// for events that concern a single page.
+	PageID string
+	Page   int
+	// Err is set for Error events.
+	Err error
+}
+
+// A ProgressFunc receives progress events.
+//
+// It is called from multiple goroutines and should return quickly.
+type ProgressFunc func(e Event)
+
+// OnProgress sets a function that receives progress events during Recognize.
+func (r *Recognizer) OnProgress(f ProgressFunc) {
+	r.progress = f
+}
+
+// emit sends an event to the progress function, if there is one.
+func (r *Recognizer) emit(e Event) {
+	if r.progress == nil {
+		return
+	}
+	e.Time = time.Now()
+	r.progress(e)
+}
+
+// as returns a copy of the event with the given type.
+func (e Event) as(t EventType) Event {
+	e.Type = t
+	return e
+}
+
+// failed returns a copy of the event for the given error.
+func (e Event) failed(err error) Event {
+	e.Type = Error
+	e.Err = err
+	return e
+}
@@ -0,0 +1,114 @@
+package rescript
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/akeil/rmtool/pkg/lines"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgressCacheHit(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "rescript-test")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	var mx sync.Mutex
+	events := make([]Event, 0)
+	r := NewRecognizer("", "", dir)
+	r.OnProgress(func(e Event) {
+		mx.Lock()
+		events = append(events, e)
+		mx.Unlock()
+	})
+
+	o := newOptions([]Option{SeparateLayers()})
+	d := &lines.Drawing{
+		Layers: []lines.Layer{
+			lines.Layer{Strokes: []lines.Stroke{
+				sampleStroke(lines.Fineliner, 100, 100, 600, 150),
+			}},
+			lines.Layer{Strokes: []lines.Stroke{
+				sampleStroke(lines.Fineliner, 100, 800, 600, 850),
+			}},
+		},
+	}
+	for _, u := range o.units(d) {
+		k, err := cacheKey(newRequest(u.groups, LangEN, o.converter.Transform))
+		assert.Nil(err)
+		assert.Nil(r.writeCache(k, Result{}))
+	}
+
+	ev := Event{Document: "doc", PageID: "page", Page: 3}
+	_, err = r.recognizePage(d, LangEN, o, ev)
+	assert.Nil(err)
+
+	assert.Equal(2, len(events))
+	for _, e := range events {
+		assert.Equal(CacheHit, e.Type)
+		assert.Equal("doc", e.Document)
+		assert.Equal("page", e.PageID)
+		assert.Equal(3, e.Page)
+		assert.False(e.Time.IsZero())
+	}
+	assert.Equal("cache-hit", CacheHit.String())
+}
+
+func TestProgressRequestSent(t *testing.T) {
+	assert := assert.New(t)
+
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	var mx sync.Mutex
+	events := make([]EventType, 0)
+	r := NewRecognizer("app", "hmac", "")
+	r.ms.host = server.URL
+	r.OnProgress(func(e Event) {
+		mx.Lock()
+		events = append(events, e.Type)
+		mx.Unlock()
+	})
+	u := NewUsage()
+	u.MonthlyLimit = 2
+	r.TrackUsage(u)
+
+	o := newOptions(nil)
+	d := &lines.Drawing{
+		Layers: []lines.Layer{
+			lines.Layer{Strokes: []lines.Stroke{
+				sampleStroke(lines.Fineliner, 100, 100, 600, 150),
+			}},
+		},
+	}
+
+	_, err := r.recognizePage(d, LangEN, o, Event{})
+	assert.Nil(err)
+	assert.Equal([]EventType{RequestSent}, events)
+
+	// a failed request was still sent
+	status = http.StatusBadRequest
+	events = events[:0]
+	_, err = r.recognizePage(d, LangEN, o, Event{})
+	assert.Error(err)
+	assert.Equal([]EventType{RequestSent}, events)
+
+	// a refused request was not
+	events = events[:0]
+	_, err = r.recognizePage(d, LangEN, o, Event{})
+	assert.True(errors.Is(err, ErrLimitExceeded))
+	assert.Equal([]EventType{}, events)
+	assert.Equal(2, u.Month(time.Now()).Requests)
+}
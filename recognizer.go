@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	"golang.org/x/sync/errgroup"
@@ -24,6 +25,7 @@ type Recognizer struct {
 	ms       *MyScript
 	cacheDir string
 	cacheMx  sync.RWMutex
+	progress ProgressFunc
 }
 
 // NewRecognizer creates a recognizer withthe given credentials for the
@@ -38,27 +40,133 @@ func NewRecognizer(appKey, hmacKey, cacheDir string) *Recognizer {
 	}
 }
 
-// Recognize performs handwriting recognition on all pages of the given document.
+// TrackUsage counts the requests to the MyScript API in the given Usage
+// and enforces its monthly limit.
+func (r *Recognizer) TrackUsage(u *Usage) {
+	r.ms.TrackUsage(u)
+}
+
+// An Option changes the behavior of a call to Recognize.
+type Option func(o *options)
+
+type options struct {
+	separateLayers bool
+	segment        bool
+	blockGap       float64
+	converter      *Converter
+	pages          map[int]bool
+	skipLayers     map[string]bool
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		converter: NewConverter(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// SeparateLayers makes the recognizer send each layer of a drawing in a
+// separate request instead of merging all layers into one.
+//
+// Tokens from the results remember the index of their layer
+// and composers can place the layers in separate sections.
+func SeparateLayers() Option {
+	return func(o *options) {
+		o.separateLayers = true
+	}
+}
+
+// SegmentBlocks makes the recognizer split each page into blocks of text
+// (e.g. columns or notes in the margin) which are recognized separately.
+//
+// Strokes which are less than gap pixels apart belong to the same block.
+// If gap is zero or less, a default value is used.
+// The results for the blocks are joined in reading order.
+func SegmentBlocks(gap float64) Option {
+	return func(o *options) {
+		o.segment = true
+		o.blockGap = gap
+		if gap <= 0 {
+			o.blockGap = DefaultBlockGap
+		}
+	}
+}
+
+// WithConverter sets the Converter that turns drawings into MyScript strokes.
+func WithConverter(c *Converter) Option {
+	return func(o *options) {
+		o.converter = c
+	}
+}
+
+// SelectPages restricts recognition to the pages with the given numbers
+// (starting at 1). Numbers that do not refer to a page are ignored.
+//
+// Use Metadata.Subset to make composers output only the selected pages.
+func SelectPages(numbers ...int) Option {
+	return func(o *options) {
+		o.pages = make(map[int]bool)
+		for _, n := range numbers {
+			o.pages[n] = true
+		}
+	}
+}
+
+// SkipLayers leaves out the layers with the given names (case insensitive)
+// so that their strokes are not recognized.
+//
+// Unlike the ExcludeLayers ComposeOption, this works whether or not layers
+// are recognized separately.
+func SkipLayers(names ...string) Option {
+	return func(o *options) {
+		o.skipLayers = make(map[string]bool)
+		for _, name := range names {
+			o.skipLayers[strings.ToLower(name)] = true
+		}
+	}
+}
+
+// Recognize performs handwriting recognition on all pages of the given document
+// (or the pages given with SelectPages).
 // It resturns a map of page-IDs and recognition results.
-func (r *Recognizer) Recognize(doc *rmtool.Document, l LanguageCode) (map[string]*Node, error) {
+func (r *Recognizer) Recognize(doc *rmtool.Document, l LanguageCode, opts ...Option) (map[string]*Node, error) {
+	o := newOptions(opts).forDocument(doc)
+
+	ev := Event{Document: doc.ID(), Title: doc.Name()}
+	r.emit(ev.as(DocumentStarted))
+
 	var resultsMx sync.Mutex
 	results := make(map[string]*Node)
 
 	var group errgroup.Group
-	for _, p := range doc.Pages() {
+	for i, p := range doc.Pages() {
+		if !o.selected(i) {
+			continue
+		}
 		pageID := p
+		pev := ev
+		pev.PageID = pageID
+		pev.Page = i + 1
 		group.Go(func() error {
 			d, err := doc.Drawing(pageID)
 			if err != nil {
+				r.emit(pev.failed(err))
 				return err
 			}
-			res, err := r.recognizeDrawing(d, l)
+			d = o.withoutSkipped(doc, pageID, d)
+			r.emit(pev.as(PageLoaded))
+			tokens, err := r.recognizePage(d, l, o, pev)
 			if err != nil {
+				r.emit(pev.failed(err))
 				return err
 			}
 			resultsMx.Lock()
-			results[pageID] = toTokens(res)
+			results[pageID] = tokens
 			resultsMx.Unlock()
+			r.emit(pev.as(PageDone))
 			return nil
 		})
 	}
@@ -68,30 +176,218 @@ func (r *Recognizer) Recognize(doc *rmtool.Document, l LanguageCode) (map[string
 		return results, err
 	}
 
+	r.emit(ev.as(DocumentDone))
 	return results, nil
 }
 
-func (r *Recognizer) recognizeDrawing(d *lines.Drawing, l LanguageCode) (Result, error) {
-	groups := make([]StrokeGroup, len(d.Layers))
-	t := int64(0)
+// A RequestEstimate describes a request that Recognize would send
+// to the MyScript API.
+type RequestEstimate struct {
+	// Layer is the index of the layer if layers are recognized separately.
+	Layer   int
+	Strokes int
+	Points  int
+	// Cached tells if the result is in the cache.
+	// Cached requests are not sent to the API.
+	Cached bool
+}
+
+// A PageEstimate lists the requests for one page of a document.
+type PageEstimate struct {
+	PageID string
+	// Number is the page number, starting at 1.
+	Number   int
+	Requests []RequestEstimate
+}
+
+// Estimate determines the requests that Recognize would send for the given
+// document and options, without calling the MyScript API.
+//
+// Pages without strokes have no requests.
+func (r *Recognizer) Estimate(doc *rmtool.Document, l LanguageCode, opts ...Option) ([]PageEstimate, error) {
+	o := newOptions(opts).forDocument(doc)
+
+	pages := make([]PageEstimate, 0)
+	for i, pageID := range doc.Pages() {
+		if !o.selected(i) {
+			continue
+		}
+		d, err := doc.Drawing(pageID)
+		if err != nil {
+			return pages, err
+		}
+		d = o.withoutSkipped(doc, pageID, d)
+		pages = append(pages, PageEstimate{
+			PageID:   pageID,
+			Number:   i + 1,
+			Requests: r.estimatePage(d, l, o),
+		})
+	}
+
+	return pages, nil
+}
+
+func (r *Recognizer) estimatePage(d *lines.Drawing, l LanguageCode, o options) []RequestEstimate {
+	requests := make([]RequestEstimate, 0)
+	for _, u := range o.units(d) {
+		e := RequestEstimate{Layer: u.layer}
+		for _, g := range u.groups {
+			e.Strokes += len(g.Strokes)
+			for _, s := range g.Strokes {
+				e.Points += len(s.X)
+			}
+		}
+
+		k, err := cacheKey(newRequest(u.groups, l, o.converter.Transform))
+		if err == nil {
+			e.Cached = r.isCached(k)
+		}
+		requests = append(requests, e)
+	}
+	return requests
+}
+
+// forDocument adjusts the options for the orientation of a document.
+func (o options) forDocument(doc *rmtool.Document) options {
+	if doc.Orientation() == rmtool.Landscape {
+		// copy, do not modify the converter passed in the options
+		c := *o.converter
+		c.Transform.Landscape = true
+		o.converter = &c
+	}
+	return o
+}
+
+// selected tells if the page with the given index should be recognized.
+func (o options) selected(idx int) bool {
+	return o.pages == nil || o.pages[idx+1]
+}
+
+// withoutSkipped returns a copy of the drawing in which the layers to skip
+// are empty. The indices of the other layers are kept.
+func (o options) withoutSkipped(doc *rmtool.Document, pageID string, d *lines.Drawing) *lines.Drawing {
+	if len(o.skipLayers) == 0 {
+		return d
+	}
+
+	m := Metadata{Layers: map[string][]string{pageID: layerNames(doc, pageID)}}
+	c := *d
+	c.Layers = make([]lines.Layer, len(d.Layers))
 	for i, l := range d.Layers {
-		g, tx := ConvertLayer(t, l)
-		t = tx
-		groups[i] = g
+		if !o.skipLayers[strings.ToLower(m.LayerName(pageID, i))] {
+			c.Layers[i] = l
+		}
 	}
+	return &c
+}
 
-	req := prepareRequest(l)
-	req.StrokeGroups = groups
+// recognizePage sends the requests for the given drawing and joins the results
+// into one list of tokens.
+//
+// The results from separate requests are separated by an empty line.
+//
+// Progress events for the requests are sent for the page in ev.
+func (r *Recognizer) recognizePage(d *lines.Drawing, l LanguageCode, o options, ev Event) (*Node, error) {
+	var tail *Node
+	var head *Node
+	for _, u := range o.units(d) {
+		res, err := r.recognizeGroups(u.groups, l, o.converter.Transform, ev)
+		if err != nil {
+			return tail, err
+		}
+
+		n := toTokens(res)
+		if n == nil {
+			continue
+		}
+
+		if head != nil {
+			// the separator belongs to the previous unit
+			for i := 0; i < 2; i++ {
+				sep := NewNode(NewToken("\n"))
+				sep.Token().layer = head.Token().layer
+				head.InsertAfter(sep)
+				head = sep
+			}
+			head.join(n)
+		} else {
+			tail = n
+		}
+
+		for node := n; node != nil; node = node.Next() {
+			t := node.Token()
+			t.layer = u.layer
+			t.bounds = o.converter.Transform.ToDevice(t.bounds)
+			head = node
+		}
+	}
+
+	return tail, nil
+}
+
+// A unit is a set of stroke groups that is recognized with a single request.
+type unit struct {
+	layer  int
+	groups []StrokeGroup
+}
+
+// units splits a drawing into the units that are sent to the API.
+// Units without strokes are omitted.
+func (o options) units(d *lines.Drawing) []unit {
+	units := make([]unit, 0)
+
+	if !o.separateLayers && !o.segment {
+		// all layers in one request
+		groups := make([]StrokeGroup, 0)
+		t := int64(0)
+		for _, l := range d.Layers {
+			g, tx := o.converter.ConvertLayer(t, l)
+			t = tx
+			groups = append(groups, g...)
+		}
+		if len(groups) > 0 {
+			units = append(units, unit{groups: groups})
+		}
+		return units
+	}
+
+	layers := d.Layers
+	if !o.separateLayers {
+		layers = []lines.Layer{mergeLayers(d.Layers)}
+	}
+
+	for i, l := range layers {
+		blocks := []lines.Layer{l}
+		if o.segment {
+			blocks = SegmentLayer(o.converter.filter(l), o.blockGap)
+		}
+		for _, b := range blocks {
+			g, _ := o.converter.ConvertLayer(0, b)
+			if len(g) > 0 {
+				units = append(units, unit{layer: i, groups: g})
+			}
+		}
+	}
+
+	return units
+}
+
+func (r *Recognizer) recognizeGroups(groups []StrokeGroup, l LanguageCode, t Transform, ev Event) (Result, error) {
+	req := newRequest(groups, l, t)
 
 	k, err := cacheKey(req)
 	if err == nil {
 		cached, err := r.readCache(k)
 		if err == nil {
+			r.emit(ev.as(CacheHit))
 			return cached, nil
 		}
 	}
 
-	res, err := r.ms.Batch(req)
+	// not sent if the request is refused, e.g. because of the usage limit
+	res, err := r.ms.batch(req, func() {
+		r.emit(ev.as(RequestSent))
+	})
 	if err != nil {
 		return res, err
 	}
@@ -128,6 +424,19 @@ func (r *Recognizer) readCache(key string) (Result, error) {
 	return res, nil
 }
 
+// isCached tells if there is a cached result for the given key.
+func (r *Recognizer) isCached(key string) bool {
+	if r.cacheDir == "" {
+		return false
+	}
+
+	r.cacheMx.RLock()
+	defer r.cacheMx.RUnlock()
+
+	_, err := os.Stat(filepath.Join(r.cacheDir, key+".cache.json"))
+	return err == nil
+}
+
 func (r *Recognizer) writeCache(key string, res Result) error {
 	if r.cacheDir == "" {
 		return fmt.Errorf("cache dir not set")
@@ -152,10 +461,9 @@ func (r *Recognizer) writeCache(key string, res Result) error {
 	return json.NewEncoder(f).Encode(res)
 }
 
-func prepareRequest(l LanguageCode) Request {
+func prepareRequest(l LanguageCode, t Transform) Request {
 	req := NewRequest()
-	req.Width = lines.MaxWidth
-	req.Height = lines.MaxHeight
+	req.Width, req.Height = t.Size()
 	guides := false // recommended to turn off in Offscreen usage
 	bbox := true
 	chars := false
@@ -165,6 +473,13 @@ func prepareRequest(l LanguageCode) Request {
 	return req
 }
 
+// newRequest creates the request for the given stroke groups.
+func newRequest(groups []StrokeGroup, l LanguageCode, t Transform) Request {
+	req := prepareRequest(l, t)
+	req.StrokeGroups = groups
+	return req
+}
+
 func cacheKey(req Request) (string, error) {
 	cs := sha1.New()
 	req.checksum(cs)
@@ -179,7 +494,10 @@ func toTokens(r Result) *Node {
 	var tail *Node
 	var curr *Node
 	for _, w := range r.Words {
-		curr = NewNode(NewToken(w.Label))
+		t := NewToken(w.Label)
+		t.bounds = w.BoundingBox
+		t.candidates = w.Candidates
+		curr = NewNode(t)
 		if head != nil {
 			head.InsertAfter(curr)
 			head = curr
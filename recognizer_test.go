@@ -1,9 +1,14 @@
 package rescript
 
 import (
+	"io/ioutil"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/akeil/rmtool"
+	"github.com/akeil/rmtool/pkg/lines"
 )
 
 func TestWordsToTokens(t *testing.T) {
@@ -34,3 +39,100 @@ func TestWordsToTokens(t *testing.T) {
 
 	assert.True(n.IsHead())
 }
+
+func TestEstimatePage(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "rescript-test")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	r := NewRecognizer("", "", dir)
+	o := newOptions(nil)
+	d := &lines.Drawing{
+		Layers: []lines.Layer{
+			lines.Layer{Strokes: []lines.Stroke{
+				sampleStroke(lines.Fineliner, 100, 100, 600, 150),
+				sampleStroke(lines.Fineliner, 100, 200, 600, 250),
+			}},
+			lines.Layer{Strokes: []lines.Stroke{
+				sampleStroke(lines.Fineliner, 100, 800, 600, 850),
+			}},
+			lines.Layer{},
+		},
+	}
+
+	// all layers in one request
+	est := r.estimatePage(d, LangEN, o)
+	assert.Equal(1, len(est))
+	assert.Equal(3, est[0].Strokes)
+	assert.Equal(6, est[0].Points)
+	assert.False(est[0].Cached)
+
+	// one request per layer, empty layers are skipped
+	o = newOptions([]Option{SeparateLayers()})
+	est = r.estimatePage(d, LangEN, o)
+	assert.Equal(2, len(est))
+	assert.Equal(0, est[0].Layer)
+	assert.Equal(2, est[0].Strokes)
+	assert.Equal(1, est[1].Layer)
+	assert.Equal(1, est[1].Strokes)
+
+	// a cached result is predicted with the same key as Recognize uses
+	u := o.units(d)[1]
+	k, err := cacheKey(newRequest(u.groups, LangEN, o.converter.Transform))
+	assert.Nil(err)
+	assert.Nil(r.writeCache(k, Result{}))
+
+	est = r.estimatePage(d, LangEN, o)
+	assert.False(est[0].Cached)
+	assert.True(est[1].Cached)
+}
+
+func TestSkipLayers(t *testing.T) {
+	assert := assert.New(t)
+
+	doc := rmtool.NewNotebook("test", "")
+	pageID := doc.Pages()[0]
+	d := &lines.Drawing{
+		Layers: []lines.Layer{
+			lines.Layer{Strokes: []lines.Stroke{
+				sampleStroke(lines.Fineliner, 100, 100, 600, 150),
+			}},
+			lines.Layer{Strokes: []lines.Stroke{
+				sampleStroke(lines.Fineliner, 100, 800, 600, 850),
+			}},
+		},
+	}
+
+	o := newOptions(nil)
+	assert.Equal(d, o.withoutSkipped(doc, pageID, d))
+
+	// the skipped layer is empty, the other keeps its index
+	o = newOptions([]Option{SkipLayers("layer 1")})
+	s := o.withoutSkipped(doc, pageID, d)
+	assert.Equal(2, len(s.Layers))
+	assert.Equal(0, len(s.Layers[0].Strokes))
+	assert.Equal(1, len(s.Layers[1].Strokes))
+	assert.Equal(1, len(d.Layers[0].Strokes))
+
+	// also without SeparateLayers, only the other layer is recognized
+	u := o.units(s)
+	assert.Equal(1, len(u))
+	assert.Equal(1, len(u[0].groups[0].Strokes))
+}
+
+func TestCacheKeyPenStyle(t *testing.T) {
+	assert := assert.New(t)
+
+	c := NewConverter()
+	fineliner, _ := c.ConvertLayer(0, lines.Layer{Strokes: []lines.Stroke{sampleStroke(lines.Fineliner, 100, 100, 200, 100)}})
+	marker, _ := c.ConvertLayer(0, lines.Layer{Strokes: []lines.Stroke{sampleStroke(lines.Marker, 100, 100, 200, 100)}})
+	assert.Equal(fineliner[0].Strokes, marker[0].Strokes)
+
+	k1, err := cacheKey(newRequest(fineliner, LangEN, c.Transform))
+	assert.Nil(err)
+	k2, err := cacheKey(newRequest(marker, LangEN, c.Transform))
+	assert.Nil(err)
+	assert.NotEqual(k1, k2)
+}
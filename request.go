@@ -20,7 +20,7 @@ const (
 	defaultContentType = "Text"
 	defaultConversion  = "DIGITAL_EDIT"
 	defaultPenStyle    = "color: #000000; -myscript-pen-width: ;"
-	defaultResolution  = 96
+	defaultResolution  = DeviceResolution
 	singlePointerID    = -1
 )
 
@@ -81,6 +81,7 @@ func NewStrokeGroup() StrokeGroup {
 // checksum is used internally to determine the cache key.
 // It calculates a checksum over all relevant request parameters.
 func (s StrokeGroup) checksum(h hash.Hash) {
+	h.Write([]byte(s.PenStyle))
 	for _, st := range s.Strokes {
 		st.checksum(h)
 	}
@@ -2,14 +2,273 @@ package rescript
 
 import (
 	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/akeil/rmtool"
 )
 
 // Metadata holds information about a document.
 type Metadata struct {
+	ID      string
 	Title   string
 	PageIDs []string
+	// PageNumbers holds the page number in the original document for each
+	// entry in PageIDs. It is nil if all pages are included.
+	PageNumbers []int
+	// Layers holds the names of the layers for each page, keyed by page ID.
+	Layers map[string][]string
+	// Path holds the names of the parent folders, starting at the root.
+	Path []string
+	// Modified is the time of the last change, zero if unknown.
+	Modified time.Time
+	// Language is the language used for recognition.
+	Language LanguageCode
+	// Tags are user defined labels for the document.
+	Tags []string
+	// doc is the source document, required by composers that render pages.
+	doc *rmtool.Document
+	// pageCount is the number of pages in the document if this is a subset.
+	pageCount int
+}
+
+// NewMetadata collects the metadata for the given document.
+func NewMetadata(doc *rmtool.Document) Metadata {
+	m := Metadata{
+		ID:       doc.ID(),
+		Title:    doc.Name(),
+		PageIDs:  doc.Pages(),
+		Layers:   make(map[string][]string),
+		Modified: doc.LastModified(),
+		doc:      doc,
+	}
+
+	for _, pageID := range doc.Pages() {
+		names := layerNames(doc, pageID)
+		if names != nil {
+			m.Layers[pageID] = names
+		}
+	}
+
+	return m
+}
+
+// layerNames returns the names of the layers on a page
+// or nil if the page has no metadata.
+func layerNames(doc *rmtool.Document, pageID string) []string {
+	p, err := doc.Page(pageID)
+	if err != nil {
+		// not all pages have metadata
+		return nil
+	}
+	names := make([]string, len(p.Layers()))
+	for i, l := range p.Layers() {
+		names[i] = l.Name
+	}
+	return names
+}
+
+// PageNumber returns the number of the page at the given index in PageIDs,
+// starting at 1. If only some pages are included, this is the number of the
+// page in the original document.
+func (m Metadata) PageNumber(idx int) int {
+	if idx < len(m.PageNumbers) {
+		return m.PageNumbers[idx]
+	}
+	return idx + 1
+}
+
+// PageCount returns the number of pages in the document,
+// including pages that are not in a Subset.
+func (m Metadata) PageCount() int {
+	if m.pageCount != 0 {
+		return m.pageCount
+	}
+	return len(m.PageIDs)
+}
+
+// Subset returns a copy of the metadata which only includes the pages with
+// the given numbers (starting at 1).
+// The pages keep their original numbers.
+func (m Metadata) Subset(numbers []int) Metadata {
+	selected := make(map[int]bool)
+	for _, n := range numbers {
+		selected[n] = true
+	}
+
+	s := m
+	s.pageCount = m.PageCount()
+	s.PageIDs = make([]string, 0)
+	s.PageNumbers = make([]int, 0)
+	for i, pageID := range m.PageIDs {
+		number := m.PageNumber(i)
+		if selected[number] {
+			s.PageIDs = append(s.PageIDs, pageID)
+			s.PageNumbers = append(s.PageNumbers, number)
+		}
+	}
+	return s
+}
+
+// DocumentLess tells if document a comes before b if several documents are
+// combined, e.g. into the chapters of a Book.
+//
+// Documents are sorted by folder, then by title and ID, ignoring case.
+// Documents in a folder come before the documents in its subfolders.
+func DocumentLess(a, b Metadata) bool {
+	for i := 0; i < len(a.Path) && i < len(b.Path); i++ {
+		if a.Path[i] != b.Path[i] {
+			return foldLess(a.Path[i], b.Path[i])
+		}
+	}
+	if len(a.Path) != len(b.Path) {
+		return len(a.Path) < len(b.Path)
+	}
+	if a.Title != b.Title {
+		return foldLess(a.Title, b.Title)
+	}
+	return a.ID < b.ID
+}
+
+// foldLess compares two strings ignoring case.
+// Strings that only differ in case are ordered by their bytes,
+// so that they are never mixed with each other.
+func foldLess(a, b string) bool {
+	x, y := strings.ToLower(a), strings.ToLower(b)
+	if x != y {
+		return x < y
+	}
+	return a < b
+}
+
+// LayerName returns the name of a layer on the given page.
+// If the name is unknown, a generic name is generated from the layer index.
+func (m Metadata) LayerName(pageID string, layer int) string {
+	names := m.Layers[pageID]
+	if layer < len(names) && names[layer] != "" {
+		return names[layer]
+	}
+	return "Layer " + strconv.Itoa(layer+1)
+}
+
+// Folder returns the path of the parent folder, e.g. "Work/Meetings".
+func (m Metadata) Folder() string {
+	return strings.Join(m.Path, "/")
 }
 
 // ComposeFunc is a function that generates an output document from the given
 // set of tokens. THe result is written to the given writer.
 type ComposeFunc func(w io.Writer, m Metadata, r map[string]*Node) error
+
+// A ComposeOption changes the way a composer lays out the recognized text.
+type ComposeOption func(o *composeOptions)
+
+type composeOptions struct {
+	splitLayers   bool
+	excludeLayers map[string]bool
+	frontMatter   bool
+	structure     bool
+}
+
+func newComposeOptions(opts []ComposeOption) composeOptions {
+	o := composeOptions{
+		excludeLayers: make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// SplitLayers makes the composer place the text from each layer
+// in a separate section, headed by the layer name.
+func SplitLayers() ComposeOption {
+	return func(o *composeOptions) {
+		o.splitLayers = true
+	}
+}
+
+// ExcludeLayers drops the text from layers with the given names
+// (case insensitive).
+//
+// Tokens only know their layer if the document was recognized with
+// SeparateLayers; otherwise, use the SkipLayers Option for Recognize.
+func ExcludeLayers(names ...string) ComposeOption {
+	return func(o *composeOptions) {
+		for _, name := range names {
+			o.excludeLayers[strings.ToLower(name)] = true
+		}
+	}
+}
+
+// FrontMatter makes the composer start the document with a YAML front matter
+// that holds the metadata for the notebook.
+//
+// This is supported by the markdown composer and is useful for tools like
+// Obsidian, Hugo or Jekyll.
+func FrontMatter() ComposeOption {
+	return func(o *composeOptions) {
+		o.frontMatter = true
+	}
+}
+
+// InferStructure makes the composer detect headings, lists and task items
+// in the recognized text and format them accordingly.
+//
+// Headings are detected from lines that are written larger than the rest
+// or are underlined; list items from markers like "-", "1." or "[ ]".
+func InferStructure() ComposeOption {
+	return func(o *composeOptions) {
+		o.structure = true
+	}
+}
+
+// A section is a sequence of tokens from one page that should be output
+// together. The name is only set if layers are split into sections.
+type section struct {
+	layer  int
+	name   string
+	tokens []*Token
+}
+
+// sections groups the tokens for a page into sections according to the
+// compose options.
+//
+// Without options, a single section with all tokens is returned.
+func (o composeOptions) sections(m Metadata, pageID string, n *Node) []section {
+	s := make([]section, 0)
+	var curr *section
+	for node := n; node != nil; node = node.Next() {
+		t := node.Token()
+		name := m.LayerName(pageID, t.Layer())
+		if o.excludeLayers[strings.ToLower(name)] {
+			continue
+		}
+
+		if curr == nil || (o.splitLayers && curr.layer != t.Layer()) {
+			s = append(s, section{layer: t.Layer(), tokens: make([]*Token, 0)})
+			curr = &s[len(s)-1]
+			if o.splitLayers {
+				curr.name = name
+			}
+		}
+		curr.tokens = append(curr.tokens, t)
+	}
+
+	if o.splitLayers {
+		for i := range s {
+			s[i].tokens = trimNewlines(s[i].tokens)
+		}
+	}
+
+	return s
+}
+
+// trimNewlines removes trailing newline tokens.
+func trimNewlines(t []*Token) []*Token {
+	for len(t) > 0 && t[len(t)-1].IsNewline() {
+		t = t[:len(t)-1]
+	}
+	return t
+}
@@ -0,0 +1,52 @@
+package rescript
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubset(t *testing.T) {
+	assert := assert.New(t)
+
+	m := Metadata{PageIDs: []string{"a", "b", "c", "d", "e"}}
+	assert.Equal(2, m.PageNumber(1))
+
+	s := m.Subset([]int{5, 2, 4, 9})
+	assert.Equal([]string{"b", "d", "e"}, s.PageIDs)
+	assert.Equal(2, s.PageNumber(0))
+	assert.Equal(5, s.PageNumber(2))
+	// the original is unchanged
+	assert.Equal(5, len(m.PageIDs))
+	assert.Equal(5, m.PageCount())
+	assert.Equal(5, s.PageCount())
+
+	// subset of a subset keeps the original numbers
+	s = s.Subset([]int{4})
+	assert.Equal([]string{"d"}, s.PageIDs)
+	assert.Equal(4, s.PageNumber(0))
+	assert.Equal(5, s.PageCount())
+}
+
+func TestDocumentLess(t *testing.T) {
+	assert := assert.New(t)
+
+	// in the expected order
+	docs := []Metadata{
+		{ID: "1", Title: "apple"},
+		{ID: "2", Title: "Zebra"},
+		{ID: "3", Title: "Old", Path: []string{"Archive"}},
+		// folders that only differ in case are not mixed
+		{ID: "4", Title: "Plan", Path: []string{"Work"}},
+		{ID: "5", Title: "agenda", Path: []string{"work"}},
+		{ID: "6", Title: "Notes", Path: []string{"work"}},
+		{ID: "7", Title: "Notes", Path: []string{"work"}},
+		{ID: "8", Title: "notes", Path: []string{"work"}},
+		{ID: "9", Title: "Minutes", Path: []string{"work", "Meetings"}},
+	}
+	for i, a := range docs {
+		for j, b := range docs {
+			assert.Equal(i < j, DocumentLess(a, b), "%v < %v", a.ID, b.ID)
+		}
+	}
+}
@@ -1,5 +1,9 @@
 package rescript
 
+import (
+	"math"
+)
+
 // Result is the response returned by the MyScript batch enpoint.
 //
 // The Label field contains the complete recognized text.
@@ -71,3 +75,22 @@ type BoundingBox struct {
 func (b BoundingBox) IsZero() bool {
 	return b.X == 0 && b.Y == 0 && b.Width == 0 && b.Height == 0
 }
+
+// Union returns the smallest bounding box that contains both boxes.
+// Zero boxes are ignored.
+func (b BoundingBox) Union(o BoundingBox) BoundingBox {
+	if b.IsZero() {
+		return o
+	}
+	if o.IsZero() {
+		return b
+	}
+	x := math.Min(b.X, o.X)
+	y := math.Min(b.Y, o.Y)
+	return BoundingBox{
+		X:      x,
+		Y:      y,
+		Width:  math.Max(b.X+b.Width, o.X+o.Width) - x,
+		Height: math.Max(b.Y+b.Height, o.Y+o.Height) - y,
+	}
+}
@@ -0,0 +1,314 @@
+package rescript
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A blockKind describes the role of a block of text.
+type blockKind int
+
+const (
+	paragraph blockKind = iota
+	heading
+	bulletItem
+	numberedItem
+	taskItem
+)
+
+const (
+	// lines written this much larger than usual are headings
+	headingRatio = 1.5
+	// lines written this much larger than usual are top-level headings
+	titleRatio = 2.0
+)
+
+// A textBlock is a paragraph, heading or list item, inferred from the layout of
+// the recognized text.
+type textBlock struct {
+	kind blockKind
+	// level is the heading level, starting at 1 for the largest headings.
+	level int
+	// number is the number of a numbered list item.
+	number int
+	// checked tells if a task item is done.
+	checked bool
+	// lines holds the text for the block, without list markers.
+	// Only paragraphs can have more than one line.
+	lines []string
+}
+
+var (
+	bulletRx   = regexp.MustCompile(`^[-*+•–]\s+(.*)$`)
+	numberedRx = regexp.MustCompile(`^(\d{1,3})[.)]\s+(.*)$`)
+	taskRx     = regexp.MustCompile(`^(?:\[([ xX✓✔]?)\]|([☐□▢☑☒✓✔]))\s*(.*)$`)
+	ruleRx     = regexp.MustCompile(`^[-_=—–]{2,}$`)
+)
+
+// inferStructure groups the tokens into blocks of text.
+//
+// It detects list items from markers at the start of a line: bullets like
+// "-" or "*", numbers like "1." or "2)" and tasks like "[ ]", "[x]" or a
+// drawn box "☐".
+//
+// Lines that are written noticeably larger than the rest of the text are
+// treated as headings, as are lines that are followed by an underline
+// ("===" for top-level headings, "---" for others).
+// Empty lines separate paragraphs.
+func inferStructure(tokens []*Token) []textBlock {
+	lns := textLines(tokens)
+	normal := medianHeight(tokens)
+
+	blocks := make([]textBlock, 0)
+	// index of the paragraph that is continued by the next line, if any
+	para := -1
+	for i, line := range lns {
+		text := strings.TrimSpace(lineText(line))
+		if text == "" {
+			para = -1
+			continue
+		}
+
+		// underlined heading, the underline is dropped;
+		// other lines separate paragraphs
+		if ruleRx.MatchString(text) {
+			if para != -1 && len(blocks[para].lines) == 1 {
+				blocks[para].kind = heading
+				blocks[para].level = 2
+				if strings.HasPrefix(text, "=") {
+					blocks[para].level = 1
+				}
+			}
+			para = -1
+			continue
+		}
+
+		b := classify(text)
+		if b.kind == paragraph {
+			h := wordsHeight(line)
+			if normal > 0 && h >= normal*titleRatio {
+				b.kind = heading
+				b.level = 1
+			} else if normal > 0 && h >= normal*headingRatio {
+				b.kind = heading
+				b.level = 2
+			}
+		}
+
+		// consecutive lines form a paragraph,
+		// unless the next line is an underline
+		underlined := i+1 < len(lns) && ruleRx.MatchString(strings.TrimSpace(lineText(lns[i+1])))
+		if b.kind == paragraph && para != -1 && !underlined {
+			blocks[para].lines = append(blocks[para].lines, text)
+			continue
+		}
+
+		blocks = append(blocks, b)
+		if b.kind == paragraph {
+			para = len(blocks) - 1
+		} else {
+			para = -1
+		}
+	}
+
+	return blocks
+}
+
+// classify detects list items from the leading characters of a line.
+// Returns a paragraph block if the line has no list marker.
+func classify(text string) textBlock {
+	// task items may be preceded by a bullet, e.g. "- [ ] task"
+	s := text
+	if m := bulletRx.FindStringSubmatch(text); m != nil {
+		s = m[1]
+	}
+	if m := taskRx.FindStringSubmatch(s); m != nil && m[3] != "" {
+		mark := m[1] + m[2]
+		checked := mark != "" && mark != " " && !strings.ContainsAny(mark, "☐□▢")
+		return textBlock{kind: taskItem, checked: checked, lines: []string{m[3]}}
+	}
+
+	if m := bulletRx.FindStringSubmatch(text); m != nil && m[1] != "" {
+		return textBlock{kind: bulletItem, lines: []string{m[1]}}
+	}
+
+	if m := numberedRx.FindStringSubmatch(text); m != nil && m[2] != "" {
+		n, _ := strconv.Atoi(m[1])
+		return textBlock{kind: numberedItem, number: n, lines: []string{m[2]}}
+	}
+
+	return textBlock{kind: paragraph, lines: []string{text}}
+}
+
+// isListItem tells if the block is an item of a list.
+func (b textBlock) isListItem() bool {
+	return b.kind == bulletItem || b.kind == numberedItem || b.kind == taskItem
+}
+
+// continues tells if the block is an item of the same list as the previous
+// block. Bullets and tasks can be mixed, numbered items cannot.
+func (b textBlock) continues(prev textBlock) bool {
+	if !b.isListItem() || !prev.isListItem() {
+		return false
+	}
+	return (b.kind == numberedItem) == (prev.kind == numberedItem)
+}
+
+// text returns the lines of the block, separated by newlines.
+func (b textBlock) text() string {
+	return strings.Join(b.lines, "\n")
+}
+
+// textLines splits a sequence of tokens into lines.
+// Unlike splitLines, whitespace tokens are kept and empty lines are included.
+func textLines(tokens []*Token) [][]*Token {
+	result := make([][]*Token, 0)
+	line := make([]*Token, 0)
+	for _, t := range tokens {
+		if t.IsNewline() {
+			result = append(result, line)
+			line = make([]*Token, 0)
+		} else {
+			line = append(line, t)
+		}
+	}
+	return append(result, line)
+}
+
+func lineText(tokens []*Token) string {
+	var b strings.Builder
+	for _, t := range tokens {
+		b.WriteString(t.String())
+	}
+	return b.String()
+}
+
+// wordsHeight returns the average height of the words on a line.
+// Returns zero if none of the words has a bounding box.
+func wordsHeight(tokens []*Token) float64 {
+	var sum float64
+	var n int
+	for _, t := range tokens {
+		if t.IsWhitespace() || t.Bounds().IsZero() {
+			continue
+		}
+		sum += t.Bounds().Height
+		n++
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
+
+// medianHeight returns the median height of the words with a bounding box.
+// Returns zero if the tokens contain less than three words with a bounding box
+// as this is not enough to tell what the normal size of the handwriting is.
+func medianHeight(tokens []*Token) float64 {
+	heights := make([]float64, 0)
+	for _, t := range tokens {
+		if t.IsWhitespace() || t.Bounds().IsZero() {
+			continue
+		}
+		heights = append(heights, t.Bounds().Height)
+	}
+	if len(heights) < 3 {
+		return 0
+	}
+	sort.Float64s(heights)
+	return heights[len(heights)/2]
+}
+
+// A blockStyle describes how blocks of text are formatted in a markup language.
+// The functions return the prefix for a heading or list item.
+type blockStyle struct {
+	heading func(level int) string
+	bullet  string
+	number  func(n int) string
+	task    func(checked bool) string
+	// escape is applied to each line of text that is not a heading or list
+	// item, if it is set.
+	escape func(line string) string
+}
+
+// format formats the blocks of text.
+//
+// Blocks are separated by an empty line, except for items of the same list.
+func (s blockStyle) format(blocks []textBlock) string {
+	var b strings.Builder
+	for i, blk := range blocks {
+		if i != 0 {
+			if blk.continues(blocks[i-1]) {
+				b.WriteString("\n")
+			} else {
+				b.WriteString("\n\n")
+			}
+		}
+
+		switch blk.kind {
+		case heading:
+			b.WriteString(s.heading(blk.level))
+		case bulletItem:
+			b.WriteString(s.bullet)
+		case numberedItem:
+			b.WriteString(s.number(blk.number))
+		case taskItem:
+			b.WriteString(s.task(blk.checked))
+		case paragraph:
+			blk.lines = s.escapeLines(blk.lines)
+		}
+		b.WriteString(blk.text())
+	}
+	return b.String()
+}
+
+// numbered formats the prefix for a numbered list item as "1. ".
+func numbered(n int) string {
+	return fmt.Sprintf("%d. ", n)
+}
+
+// text formats the given tokens. If infer is set, the structure is inferred
+// and formatted in this style; otherwise, the text is returned as it is,
+// without trailing newlines.
+func (s blockStyle) text(tokens []*Token, infer bool) string {
+	if infer {
+		return s.format(inferStructure(tokens))
+	}
+	text := strings.TrimRight(lineText(tokens), "\n")
+	return strings.Join(s.escapeLines(strings.Split(text, "\n")), "\n")
+}
+
+// escapeLines returns a copy of the lines with the escape function applied.
+func (s blockStyle) escapeLines(lines []string) []string {
+	if s.escape == nil {
+		return lines
+	}
+	escaped := make([]string, len(lines))
+	for i, l := range lines {
+		escaped[i] = s.escape(l)
+	}
+	return escaped
+}
+
+// paragraphs splits the tokens into paragraphs at empty lines,
+// without inferring any further structure.
+func paragraphs(tokens []*Token) []textBlock {
+	blocks := make([]textBlock, 0)
+	para := -1
+	for _, line := range textLines(tokens) {
+		text := strings.TrimSpace(lineText(line))
+		if text == "" {
+			para = -1
+			continue
+		}
+		if para == -1 {
+			blocks = append(blocks, textBlock{kind: paragraph})
+			para = len(blocks) - 1
+		}
+		blocks[para].lines = append(blocks[para].lines, text)
+	}
+	return blocks
+}
@@ -0,0 +1,80 @@
+package rescript
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInferStructure(t *testing.T) {
+	assert := assert.New(t)
+
+	text := "Shopping\n---\nsome text\nmore text\n\n- milk\n* eggs\n1. first\n2) second\n[ ] todo\n- [x] done\n☐ drawn"
+	blocks := inferStructure(textTokens(text))
+
+	assert.Equal(9, len(blocks))
+	assert.Equal(textBlock{kind: heading, level: 2, lines: []string{"Shopping"}}, blocks[0])
+	assert.Equal(textBlock{kind: paragraph, lines: []string{"some text", "more text"}}, blocks[1])
+	assert.Equal(textBlock{kind: bulletItem, lines: []string{"milk"}}, blocks[2])
+	assert.Equal(textBlock{kind: bulletItem, lines: []string{"eggs"}}, blocks[3])
+	assert.Equal(textBlock{kind: numberedItem, number: 1, lines: []string{"first"}}, blocks[4])
+	assert.Equal(textBlock{kind: numberedItem, number: 2, lines: []string{"second"}}, blocks[5])
+	assert.Equal(textBlock{kind: taskItem, lines: []string{"todo"}}, blocks[6])
+	assert.Equal(textBlock{kind: taskItem, checked: true, lines: []string{"done"}}, blocks[7])
+	assert.Equal(textBlock{kind: taskItem, lines: []string{"drawn"}}, blocks[8])
+}
+
+func TestClassifyNoMarker(t *testing.T) {
+	assert := assert.New(t)
+
+	// a marker must be followed by whitespace
+	for _, text := range []string{"-5 degrees", "+1", "*important*", "3.5 kg", "2.", "-"} {
+		assert.Equal(textBlock{kind: paragraph, lines: []string{text}}, classify(text), text)
+	}
+}
+
+func TestInferHeadingFromSize(t *testing.T) {
+	assert := assert.New(t)
+
+	tokens := textTokens("Big\nlarger\nsmall text\nand more")
+	heights := []float64{100, 0, 30, 0, 20, 0, 20, 0, 20, 0, 20}
+	for i, h := range heights {
+		if h != 0 {
+			tokens[i].bounds = BoundingBox{X: 10, Y: 10, Width: 50, Height: h}
+		}
+	}
+
+	blocks := inferStructure(tokens)
+	assert.Equal(3, len(blocks))
+	assert.Equal(textBlock{kind: heading, level: 1, lines: []string{"Big"}}, blocks[0])
+	assert.Equal(textBlock{kind: heading, level: 2, lines: []string{"larger"}}, blocks[1])
+	assert.Equal(paragraph, blocks[2].kind)
+}
+
+func TestMarkdownStructure(t *testing.T) {
+	assert := assert.New(t)
+
+	text := "Shopping\n===\nwe need\n- milk\n- eggs\n[x] bread\n1. first\n2. second\nthanks"
+	expected := "## Shopping\n\nwe need\n\n- milk\n- eggs\n- [x] bread\n\n1. first\n2. second\n\nthanks"
+	assert.Equal(expected, markdownStyle.format(inferStructure(textTokens(text))))
+}
+
+// textTokens creates a token for each word, space and newline in a string.
+func textTokens(s string) []*Token {
+	tokens := make([]*Token, 0)
+	for i, line := range strings.Split(s, "\n") {
+		if i != 0 {
+			tokens = append(tokens, NewToken("\n"))
+		}
+		for j, word := range strings.Split(line, " ") {
+			if j != 0 {
+				tokens = append(tokens, NewToken(" "))
+			}
+			if word != "" {
+				tokens = append(tokens, NewToken(word))
+			}
+		}
+	}
+	return tokens
+}
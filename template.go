@@ -0,0 +1,188 @@
+package rescript
+
+import (
+	"io"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+// PlaintextTemplate is a template that generates the same output as the
+// plaintext composer.
+const PlaintextTemplate = `{{ if .Title }}{{ upper .Title }}
+{{ end }}
+{{- range .Pages }}{{ if .Recognized }}
+[Page {{ .Number }}]
+
+{{ range $i, $s := .Sections }}{{ if $s.Name }}{{ if $i }}
+
+{{ end }}[{{ $s.Name }}]
+
+{{ end }}{{ $s.Text }}{{ end }}
+{{ end }}{{ end }}`
+
+// MarkdownTemplate is a template that generates the same output as the
+// markdown composer.
+const MarkdownTemplate = `# {{ .Title }}
+
+{{ range $i, $p := .Pages }}{{ if $i }}
+
+---
+
+{{ end }}{{ if $p.Recognized }}**Page {{ $p.Number }}**
+
+{{ range $j, $s := $p.Sections }}{{ if $s.Name }}{{ if $j }}
+
+{{ end }}*{{ $s.Name }}*
+
+{{ end }}{{ $s.Text }}{{ end }}{{ end }}{{ end }}
+`
+
+// NewTemplateComposer creates a composer that generates output from the given
+// text/template.
+//
+// The template is executed with the document Metadata (e.g. {{ .Title }})
+// and a list of Pages. Each page has a Number, the full Text, a list of
+// Sections (see SplitLayers), Lines and Tokens.
+//
+// Additional template functions are:
+//
+//	upper, lower   change the case of a string
+//	date           formats a time, e.g. {{ date "2006-01-02" now }}
+//	now            returns the current time
+//	join           joins a list of strings with a separator
+//	slugify        creates a URL friendly version of a string
+func NewTemplateComposer(text string, opts ...ComposeOption) (ComposeFunc, error) {
+	tpl, err := template.New("rescript").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	o := newComposeOptions(opts)
+	return func(w io.Writer, m Metadata, r map[string]*Node) error {
+		return tpl.Execute(w, newTemplateData(m, r, o))
+	}, nil
+}
+
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"date": func(layout string, t time.Time) string {
+		return t.Format(layout)
+	},
+	"now": time.Now,
+	"join": func(sep string, s []string) string {
+		return strings.Join(s, sep)
+	},
+	"slugify": slugify,
+}
+
+type templateData struct {
+	Metadata
+	Pages []templatePage
+}
+
+type templatePage struct {
+	ID         string
+	Number     int
+	Recognized bool
+	Text       string
+	Sections   []templateSection
+	Lines      []templateLine
+	Tokens     []*Token
+}
+
+type templateSection struct {
+	Name   string
+	Text   string
+	Lines  []templateLine
+	Tokens []*Token
+}
+
+type templateLine struct {
+	Text  string
+	Words []*Token
+}
+
+func newTemplateData(m Metadata, r map[string]*Node, o composeOptions) templateData {
+	d := templateData{
+		Metadata: m,
+		Pages:    make([]templatePage, len(m.PageIDs)),
+	}
+
+	for i, pageID := range m.PageIDs {
+		p := templatePage{
+			ID:       pageID,
+			Number:   m.PageNumber(i),
+			Sections: make([]templateSection, 0),
+			Lines:    make([]templateLine, 0),
+			Tokens:   make([]*Token, 0),
+		}
+
+		tail, ok := r[pageID]
+		if ok {
+			p.Recognized = true
+			texts := make([]string, 0)
+			for _, s := range o.sections(m, pageID, tail) {
+				ts := newTemplateSection(s)
+				p.Sections = append(p.Sections, ts)
+				p.Lines = append(p.Lines, ts.Lines...)
+				p.Tokens = append(p.Tokens, ts.Tokens...)
+				texts = append(texts, ts.Text)
+			}
+			p.Text = strings.Join(texts, "\n\n")
+		}
+
+		d.Pages[i] = p
+	}
+
+	return d
+}
+
+func newTemplateSection(s section) templateSection {
+	var b strings.Builder
+	for _, t := range s.tokens {
+		b.WriteString(t.String())
+	}
+
+	ts := templateSection{
+		Name:   s.name,
+		Text:   b.String(),
+		Lines:  make([]templateLine, 0),
+		Tokens: s.tokens,
+	}
+
+	for _, tokens := range textLines(s.tokens) {
+		line := words(tokens)
+		if len(line) == 0 {
+			continue
+		}
+		ts.Lines = append(ts.Lines, templateLine{
+			// the same text as the JSON composer writes
+			Text:  strings.TrimSpace(lineText(tokens)),
+			Words: line,
+		})
+	}
+
+	return ts
+}
+
+// slugify converts a string to lowercase and replaces everything but letters
+// and digits with a single dash.
+func slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteRune('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,122 @@
+package rescript
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComposeTemplate(t *testing.T) {
+	assert := assert.New(t)
+
+	m := Metadata{
+		ID:      "abc",
+		Title:   "My Title",
+		PageIDs: []string{"page0", "page1", "page2"},
+	}
+
+	nodes := map[string]*Node{
+		"page0": buildSampleList("foo", " ", "bar", "\n", "newline"),
+		"page2": buildSampleList("third page"),
+	}
+
+	tpl := `{{ slugify .Title }}{{ range .Pages }}|{{ .Number }}:{{ .Recognized }}:{{ len .Lines }}{{ range .Lines }}[{{ .Text }}]{{ end }}{{ end }}`
+	c, err := NewTemplateComposer(tpl)
+	assert.Nil(err)
+
+	var buf bytes.Buffer
+	err = c(&buf, m, nodes)
+	assert.Nil(err)
+	assert.Equal("my-title|1:true:2[foo bar][newline]|2:false:0|3:true:1[third page]", buf.String())
+}
+
+func TestTemplateLinesPunctuation(t *testing.T) {
+	assert := assert.New(t)
+
+	m := Metadata{PageIDs: []string{"page0"}}
+	nodes := map[string]*Node{
+		"page0": buildSampleList("Hello", ",", " ", "world", "!", "\n", "next", " ", "line"),
+	}
+
+	tpl := `{{ range .Pages }}{{ range .Lines }}[{{ .Text }}:{{ len .Words }}]{{ end }}{{ end }}`
+	c, err := NewTemplateComposer(tpl)
+	assert.Nil(err)
+
+	var buf bytes.Buffer
+	err = c(&buf, m, nodes)
+	assert.Nil(err)
+	// no space before punctuation, same as the JSON composer
+	assert.Equal("[Hello, world!:4][next line:2]", buf.String())
+
+	var j bytes.Buffer
+	err = NewJSONComposer()(&j, m, nodes)
+	assert.Nil(err)
+	assert.Contains(j.String(), `"text": "Hello, world!"`)
+}
+
+func TestBuiltinTemplates(t *testing.T) {
+	assert := assert.New(t)
+
+	m := Metadata{
+		Title:   "My Title",
+		PageIDs: []string{"page0", "page1", "page2"},
+		Layers: map[string][]string{
+			"page0": []string{"Text", "Notes"},
+		},
+	}
+
+	layered := buildSampleList("foo", " ", "bar", "\n", "note")
+	layered.Ahead(4).Token().layer = 1
+
+	nodes := map[string]*Node{
+		"page0": layered,
+		"page2": buildSampleList("third", " ", "page"),
+	}
+
+	cases := []struct {
+		name     string
+		tpl      string
+		composer func(...ComposeOption) ComposeFunc
+	}{
+		{"plaintext", PlaintextTemplate, NewPlaintextComposer},
+		{"markdown", MarkdownTemplate, NewMarkdownComposer},
+	}
+
+	for _, c := range cases {
+		for _, opts := range [][]ComposeOption{nil, {SplitLayers()}} {
+			var expected, actual bytes.Buffer
+
+			err := c.composer(opts...)(&expected, m, nodes)
+			assert.Nil(err)
+
+			tc, err := NewTemplateComposer(c.tpl, opts...)
+			assert.Nil(err)
+			err = tc(&actual, m, nodes)
+			assert.Nil(err)
+
+			assert.Equal(expected.String(), actual.String(), c.name)
+		}
+	}
+}
+
+func TestTemplateError(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := NewTemplateComposer("{{ .Title ")
+	assert.Error(err)
+
+	c, err := NewTemplateComposer("{{ .NoSuchField }}")
+	assert.Nil(err)
+	err = c(&bytes.Buffer{}, Metadata{}, map[string]*Node{})
+	assert.Error(err)
+}
+
+func TestSlugify(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("my-notes", slugify("My Notes"))
+	assert.Equal("meeting-2020-10-01", slugify("  Meeting: 2020/10/01!"))
+	assert.Equal("über-straße", slugify("Über Straße"))
+	assert.Equal("", slugify("---"))
+}
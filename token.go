@@ -12,19 +12,74 @@ import (
 // - consecutive whitespace is split into multiple tokens
 // - punctuation is a single token
 type Token struct {
-	text  string
-	runes []rune
+	text       string
+	runes      []rune
+	layer      int
+	bounds     BoundingBox
+	candidates []string
+	flags      []string
 }
 
 // NewToken creates a new token with the given content.
 func NewToken(s string) *Token {
-	return &Token{s, []rune(s)}
+	return &Token{text: s, runes: []rune(s)}
 }
 
 func (t *Token) String() string {
 	return t.text
 }
 
+// Layer is the index of the drawing layer this token was recognized from.
+func (t *Token) Layer() int {
+	return t.layer
+}
+
+// Bounds is the bounding box of this token in device pixels.
+// The bounding box is zero if the position of the token is unknown.
+func (t *Token) Bounds() BoundingBox {
+	return t.bounds
+}
+
+// Candidates are alternative recognition results for this token,
+// if the recognizer returned any.
+func (t *Token) Candidates() []string {
+	return t.candidates
+}
+
+// Flags are markers which are set by pipeline stages to describe how
+// a token was processed.
+func (t *Token) Flags() []string {
+	return t.flags
+}
+
+// HasFlag tells if the given flag is set for this token.
+func (t *Token) HasFlag(f string) bool {
+	for _, flag := range t.flags {
+		if flag == f {
+			return true
+		}
+	}
+	return false
+}
+
+// AddFlag sets the given flag for this token.
+func (t *Token) AddFlag(f string) {
+	if !t.HasFlag(f) {
+		t.flags = append(t.flags, f)
+	}
+}
+
+// withText creates a copy of this token with a different content.
+// All other properties are kept.
+func (t *Token) withText(s string) *Token {
+	c := *t
+	c.text = s
+	c.runes = []rune(s)
+	// do not share the flags with the original
+	c.flags = append([]string(nil), t.flags...)
+	return &c
+}
+
 func (t *Token) isSingle() bool {
 	return len(t.runes) == 1
 }
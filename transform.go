@@ -0,0 +1,120 @@
+package rescript
+
+import (
+	"math"
+
+	"github.com/akeil/rmtool/pkg/lines"
+)
+
+// DeviceResolution is the screen resolution of the reMarkable tablet in DPI.
+const DeviceResolution = 226
+
+const mmPerInch = 25.4
+
+// A Region is a rectangular area on a page, in device pixels.
+type Region struct {
+	X      float64
+	Y      float64
+	Width  float64
+	Height float64
+}
+
+// IsZero tells if this is the empty region.
+func (r Region) IsZero() bool {
+	return r.X == 0 && r.Y == 0 && r.Width == 0 && r.Height == 0
+}
+
+// Contains tells if the given point is inside this region.
+func (r Region) Contains(x, y float64) bool {
+	return x >= r.X && x <= r.X+r.Width && y >= r.Y && y <= r.Y+r.Height
+}
+
+// A Transform maps coordinates from the reMarkable device to the coordinate
+// system of a request to the MyScript API and back.
+//
+// Device coordinates are always in portrait orientation.
+type Transform struct {
+	// Landscape rotates the page by 90 degrees so that text written in
+	// landscape orientation runs from left to right.
+	Landscape bool
+	// Crop restricts recognition to a region of interest.
+	// Strokes outside of the region are dropped.
+	// The zero value disables cropping.
+	Crop Region
+}
+
+// region is the part of the page that is sent to the API.
+func (t Transform) region() Region {
+	if t.Crop.IsZero() {
+		return Region{Width: lines.MaxWidth, Height: lines.MaxHeight}
+	}
+	return t.Crop
+}
+
+// Size returns the width and height of the transformed page in pixels.
+func (t Transform) Size() (int64, int64) {
+	r := t.region()
+	w := int64(math.Round(r.Width))
+	h := int64(math.Round(r.Height))
+	if t.Landscape {
+		return h, w
+	}
+	return w, h
+}
+
+// includes tells if a stroke is inside the region of interest.
+// A stroke is included if the center of its bounding box is inside the region.
+func (t Transform) includes(s lines.Stroke) bool {
+	if t.Crop.IsZero() {
+		return true
+	}
+	if len(s.Dots) == 0 {
+		return false
+	}
+	b := strokeBounds(s)
+	return t.Crop.Contains((b.minX+b.maxX)/2, (b.minY+b.maxY)/2)
+}
+
+// Apply maps a point from device coordinates to transformed coordinates.
+func (t Transform) Apply(x, y float64) (float64, float64) {
+	r := t.region()
+	x -= r.X
+	y -= r.Y
+	if t.Landscape {
+		// the right edge of the device becomes the top of the page
+		return y, r.Width - x
+	}
+	return x, y
+}
+
+// ToDevice maps a bounding box from a recognition result back to
+// device coordinates.
+//
+// The bounding boxes from the MyScript API are in millimeters and refer to
+// the transformed page. The result is in (portrait) device pixels.
+func (t Transform) ToDevice(b BoundingBox) BoundingBox {
+	if b.IsZero() {
+		return b
+	}
+
+	px := func(mm float64) float64 {
+		return mm * DeviceResolution / mmPerInch
+	}
+	x := px(b.X)
+	y := px(b.Y)
+	w := px(b.Width)
+	h := px(b.Height)
+
+	r := t.region()
+	if t.Landscape {
+		// inverse of Apply
+		x, y, w, h = r.Width-y-h, x, h, w
+	}
+
+	return BoundingBox{
+		X:      x + r.X,
+		Y:      y + r.Y,
+		Width:  w,
+		Height: h,
+	}
+}
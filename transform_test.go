@@ -0,0 +1,93 @@
+package rescript
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/akeil/rmtool/pkg/lines"
+)
+
+func TestTransformSize(t *testing.T) {
+	assert := assert.New(t)
+
+	var tf Transform
+	w, h := tf.Size()
+	assert.Equal(int64(lines.MaxWidth), w)
+	assert.Equal(int64(lines.MaxHeight), h)
+
+	tf.Landscape = true
+	w, h = tf.Size()
+	assert.Equal(int64(lines.MaxHeight), w)
+	assert.Equal(int64(lines.MaxWidth), h)
+
+	tf.Crop = Region{X: 100, Y: 200, Width: 300, Height: 400}
+	w, h = tf.Size()
+	assert.Equal(int64(400), w)
+	assert.Equal(int64(300), h)
+}
+
+func TestTransformApply(t *testing.T) {
+	assert := assert.New(t)
+
+	var tf Transform
+	x, y := tf.Apply(10, 20)
+	assert.Equal(10.0, x)
+	assert.Equal(20.0, y)
+
+	tf.Crop = Region{X: 100, Y: 200, Width: 300, Height: 400}
+	x, y = tf.Apply(110, 220)
+	assert.Equal(10.0, x)
+	assert.Equal(20.0, y)
+
+	tf.Landscape = true
+	x, y = tf.Apply(110, 220)
+	assert.Equal(20.0, x)
+	assert.Equal(290.0, y)
+}
+
+func TestTransformToDevice(t *testing.T) {
+	assert := assert.New(t)
+
+	// one inch is DeviceResolution pixels
+	b := BoundingBox{X: 25.4, Y: 50.8, Width: 25.4, Height: 12.7}
+
+	var tf Transform
+	d := tf.ToDevice(b)
+	assert.InDelta(226.0, d.X, 0.001)
+	assert.InDelta(452.0, d.Y, 0.001)
+	assert.InDelta(226.0, d.Width, 0.001)
+	assert.InDelta(113.0, d.Height, 0.001)
+
+	// zero boxes stay zero
+	assert.True(tf.ToDevice(BoundingBox{}).IsZero())
+
+	// landscape and crop are reversed
+	tf.Landscape = true
+	tf.Crop = Region{X: 100, Y: 200, Width: 1000, Height: 1200}
+	d = tf.ToDevice(b)
+	x, y := tf.Apply(d.X+d.Width, d.Y)
+	assert.InDelta(226.0, x, 0.001)
+	assert.InDelta(452.0, y, 0.001)
+	assert.InDelta(113.0, d.Width, 0.001)
+	assert.InDelta(226.0, d.Height, 0.001)
+}
+
+func TestTransformCrop(t *testing.T) {
+	assert := assert.New(t)
+
+	c := NewConverter()
+	c.Transform.Crop = Region{X: 0, Y: 0, Width: 700, Height: 500}
+
+	l := lines.Layer{
+		Strokes: []lines.Stroke{
+			sampleStroke(lines.Fineliner, 100, 100, 200, 100),
+			sampleStroke(lines.Fineliner, 800, 100, 900, 100),
+			sampleStroke(lines.Fineliner, 100, 600, 200, 600),
+		},
+	}
+	gs, _ := c.ConvertLayer(0, l)
+	g := allStrokes(gs)
+	assert.Equal(1, len(g))
+	assert.Equal(100, g[0].X[0])
+}
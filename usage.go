@@ -0,0 +1,268 @@
+package rescript
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrLimitExceeded is returned if a request would exceed the monthly limit
+// for requests to the MyScript API.
+var ErrLimitExceeded = errors.New("monthly limit for MyScript requests exceeded")
+
+const dayFormat = "2006-01-02"
+
+// Usage counts the calls to the MyScript API per day.
+//
+// If a path is set, the counts are saved after each request so that they
+// are kept between runs and shared with other processes.
+// It is safe to use a Usage from multiple goroutines.
+type Usage struct {
+	// MonthlyLimit is the maximum number of requests per calendar month.
+	// If it is zero, there is no limit.
+	MonthlyLimit int
+	days         map[string]DailyUsage
+	path         string
+	mx           sync.Mutex
+}
+
+// DailyUsage holds the counts for one day.
+type DailyUsage struct {
+	// Requests is the number of requests sent to the API.
+	Requests int `json:"requests"`
+	// Responses is the number of successful responses.
+	Responses int `json:"responses"`
+	// Errors is the number of requests that failed.
+	Errors        int   `json:"errors"`
+	BytesSent     int64 `json:"bytesSent"`
+	BytesReceived int64 `json:"bytesReceived"`
+}
+
+func (d DailyUsage) add(other DailyUsage) DailyUsage {
+	d.Requests += other.Requests
+	d.Responses += other.Responses
+	d.Errors += other.Errors
+	d.BytesSent += other.BytesSent
+	d.BytesReceived += other.BytesReceived
+	return d
+}
+
+// NewUsage creates an empty Usage which is not saved.
+func NewUsage() *Usage {
+	return &Usage{days: make(map[string]DailyUsage)}
+}
+
+// LoadUsage reads the usage from the file at the given path.
+// If the file does not exist, usage starts at zero.
+//
+// The file is read again and updated for each request, so that the counts
+// are shared by processes that use the same file.
+func LoadUsage(path string) (*Usage, error) {
+	u := NewUsage()
+	u.path = path
+
+	err := u.load()
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// load replaces the counts with the contents of the file.
+func (u *Usage) load() error {
+	days := make(map[string]DailyUsage)
+	data, err := ioutil.ReadFile(u.path)
+	if os.IsNotExist(err) {
+		u.days = days
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(data, &days)
+	if err != nil {
+		return fmt.Errorf("failed to read usage from %q: %v", u.path, err)
+	}
+	u.days = days
+	return nil
+}
+
+// Day returns the usage for the day of the given time.
+func (u *Usage) Day(t time.Time) DailyUsage {
+	u.mx.Lock()
+	defer u.mx.Unlock()
+	return u.days[t.Format(dayFormat)]
+}
+
+// Month returns the total usage for the calendar month of the given time.
+func (u *Usage) Month(t time.Time) DailyUsage {
+	u.mx.Lock()
+	defer u.mx.Unlock()
+	return u.month(t)
+}
+
+func (u *Usage) month(t time.Time) DailyUsage {
+	var total DailyUsage
+	prefix := t.Format("2006-01-")
+	for day, d := range u.days {
+		if strings.HasPrefix(day, prefix) {
+			total = total.add(d)
+		}
+	}
+	return total
+}
+
+// Days returns the dates with usage in the month of the given time,
+// sorted and formatted like "2006-01-02".
+func (u *Usage) Days(t time.Time) []string {
+	u.mx.Lock()
+	defer u.mx.Unlock()
+
+	days := make([]string, 0)
+	prefix := t.Format("2006-01-")
+	for day := range u.days {
+		if strings.HasPrefix(day, prefix) {
+			days = append(days, day)
+		}
+	}
+	sort.Strings(days)
+	return days
+}
+
+// reserve counts a request before it is sent.
+// It returns ErrLimitExceeded if the request would exceed the monthly limit.
+func (u *Usage) reserve(t time.Time, size int) error {
+	return u.update(func() error {
+		if u.MonthlyLimit > 0 {
+			used := u.month(t).Requests
+			if used >= u.MonthlyLimit {
+				return fmt.Errorf("%w: %d of %d requests used in %v", ErrLimitExceeded, used, u.MonthlyLimit, t.Format("January 2006"))
+			}
+		}
+
+		day := t.Format(dayFormat)
+		d := u.days[day]
+		d.Requests++
+		d.BytesSent += int64(size)
+		u.days[day] = d
+		return nil
+	})
+}
+
+// record counts the response for a request.
+func (u *Usage) record(t time.Time, size int64, ok bool) error {
+	return u.update(func() error {
+		day := t.Format(dayFormat)
+		d := u.days[day]
+		if ok {
+			d.Responses++
+		} else {
+			d.Errors++
+		}
+		d.BytesReceived += size
+		u.days[day] = d
+		return nil
+	})
+}
+
+// update changes the counts with the given function.
+//
+// If the usage is saved, the file is locked, read again before the change
+// and written afterwards, so that other processes do not lose counts.
+func (u *Usage) update(change func() error) error {
+	u.mx.Lock()
+	defer u.mx.Unlock()
+
+	if u.path == "" {
+		return change()
+	}
+
+	unlock, err := lockFile(u.path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = u.load()
+	if err != nil {
+		return err
+	}
+	err = change()
+	if err != nil {
+		return err
+	}
+	return u.save()
+}
+
+const (
+	lockTimeout = 10 * time.Second
+	// a lock older than this was left behind by a process that crashed
+	lockStale = time.Minute
+)
+
+// lockFile creates a lock file and waits while it is held by another process.
+// It returns a function that releases the lock.
+func lockFile(path string) (func(), error) {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		} else if !os.IsExist(err) {
+			return nil, err
+		}
+
+		info, err := os.Stat(path)
+		if err == nil && time.Since(info.ModTime()) > lockStale {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timeout waiting for lock %q", path)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// save writes the usage to a temporary file and replaces the previous one.
+func (u *Usage) save() error {
+	if u.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(u.days, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(u.path), ".usage-*")
+	if err != nil {
+		return err
+	}
+	// no-op if the file was renamed
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(data)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), u.path)
+}
@@ -0,0 +1,151 @@
+package rescript
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsageLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	u := NewUsage()
+	u.MonthlyLimit = 3
+
+	jan := time.Date(2021, 1, 30, 12, 0, 0, 0, time.UTC)
+	assert.Nil(u.reserve(jan, 100))
+	assert.Nil(u.reserve(jan.Add(24*time.Hour), 100))
+	assert.Nil(u.reserve(jan.Add(24*time.Hour), 100))
+
+	err := u.reserve(jan, 100)
+	assert.True(errors.Is(err, ErrLimitExceeded))
+
+	// a new month starts at zero
+	feb := jan.AddDate(0, 0, 3)
+	assert.Nil(u.reserve(feb, 100))
+
+	assert.Equal(3, u.Month(jan).Requests)
+	assert.Equal(int64(300), u.Month(jan).BytesSent)
+	assert.Equal(1, u.Day(jan).Requests)
+	assert.Equal([]string{"2021-01-30", "2021-01-31"}, u.Days(jan))
+	assert.Equal(1, u.Month(feb).Requests)
+}
+
+func TestUsageSave(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "rescript-test")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "data", "usage.json")
+
+	u, err := LoadUsage(path)
+	assert.Nil(err)
+
+	now := time.Now()
+	assert.Nil(u.reserve(now, 100))
+	assert.Nil(u.record(now, 50, true))
+	assert.Nil(u.reserve(now, 10))
+	assert.Nil(u.record(now, 0, false))
+
+	u, err = LoadUsage(path)
+	assert.Nil(err)
+	d := u.Day(now)
+	assert.Equal(2, d.Requests)
+	assert.Equal(1, d.Responses)
+	assert.Equal(1, d.Errors)
+	assert.Equal(int64(110), d.BytesSent)
+	assert.Equal(int64(50), d.BytesReceived)
+}
+
+func TestUsageShared(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "rescript-test")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "usage.json")
+
+	// two processes that use the same file
+	a, err := LoadUsage(path)
+	assert.Nil(err)
+	a.MonthlyLimit = 3
+	b, err := LoadUsage(path)
+	assert.Nil(err)
+	b.MonthlyLimit = 3
+
+	now := time.Now()
+	assert.Nil(a.reserve(now, 10))
+	assert.Nil(b.reserve(now, 10))
+	assert.Nil(a.reserve(now, 10))
+	assert.Nil(b.record(now, 5, true))
+
+	// the limit counts the requests from both
+	err = b.reserve(now, 10)
+	assert.True(errors.Is(err, ErrLimitExceeded))
+
+	u, err := LoadUsage(path)
+	assert.Nil(err)
+	assert.Equal(3, u.Day(now).Requests)
+	assert.Equal(1, u.Day(now).Responses)
+	assert.Equal(int64(30), u.Day(now).BytesSent)
+	_, err = os.Stat(path + ".lock")
+	assert.True(os.IsNotExist(err))
+}
+
+func TestTrackUsage(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"label": "foo"}`))
+	}))
+	defer server.Close()
+
+	m := NewMyScript("app", "hmac")
+	m.host = server.URL
+	u := NewUsage()
+	u.MonthlyLimit = 1
+	m.TrackUsage(u)
+
+	_, err := m.Batch(NewRequest())
+	assert.Nil(err)
+	d := u.Month(time.Now())
+	assert.Equal(1, d.Requests)
+	assert.Equal(1, d.Responses)
+	assert.Equal(int64(16), d.BytesReceived)
+	assert.True(d.BytesSent > 0)
+
+	// the limit is reached, the request is not sent
+	_, err = m.Batch(NewRequest())
+	assert.True(errors.Is(err, ErrLimitExceeded))
+	assert.Equal(1, u.Month(time.Now()).Requests)
+}
+
+func TestTrackUsageError(t *testing.T) {
+	assert := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code": "bad"}`))
+	}))
+	defer server.Close()
+
+	m := NewMyScript("app", "hmac")
+	m.host = server.URL
+	u := NewUsage()
+	m.TrackUsage(u)
+
+	_, err := m.Batch(NewRequest())
+	assert.NotNil(err)
+	d := u.Month(time.Now())
+	assert.Equal(1, d.Requests)
+	assert.Equal(1, d.Errors)
+	assert.Equal(int64(15), d.BytesReceived)
+}